@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/mailer"
+	"github.com/greatdaveo/privycode-server/internal/secrets"
+	"github.com/greatdaveo/privycode-server/migrations"
+)
+
+// To print the settings the server would see, then check the parts that can
+// be checked from here: validation, keys, mailer and database
+func diagnose(args []string) error {
+	flags := newFlags("config")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *flags.databaseURL != "" {
+		cfg.DatabaseURL = *flags.databaseURL
+	}
+
+	cfg.Print(os.Stdout)
+	fmt.Println()
+
+	failed := false
+	check := func(name string, err error, detail string) {
+		if err != nil {
+			failed = true
+			fmt.Printf("❌ %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("✅ %s: %s\n", name, detail)
+	}
+
+	check("settings", cfg.Validate(), "valid")
+
+	keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+	activeKey := ""
+	if err == nil {
+		activeKey = "active key " + keyring.ActiveKeyID()
+	}
+	check("encryption keys", err, activeKey)
+
+	_, err = mailer.New(cfg.Mail, log.Default())
+	check("mailer", err, cfg.Mail.Kind)
+
+	db, err := config.OpenDB(cfg.DatabaseURL)
+	check("database", err, "connected")
+
+	if err == nil {
+		status, err := migrations.Current(db)
+		detail := fmt.Sprintf("version %d of %d", status.Version, status.Latest)
+		if err == nil && !status.Current() {
+			err = fmt.Errorf("%s, run `server migrate up`", detail)
+		}
+		check("schema", err, detail)
+	}
+
+	if failed {
+		return errors.New("configuration has problems")
+	}
+
+	return nil
+}
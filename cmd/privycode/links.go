@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/jobs"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+func listLinks(args []string) error {
+	flags := newFlags("links")
+	ref := flags.String("user", "", "GitHub username or user ID")
+	deleted := flags.Bool("deleted", false, "include deleted links")
+	if err := flags.connect(args); err != nil {
+		return err
+	}
+
+	user, err := findUser(*ref)
+	if err != nil {
+		return err
+	}
+
+	query := db
+	if *deleted {
+		query = query.Unscoped()
+	}
+
+	var links []models.ViewerLink
+	if err := query.Where("user_id = ?", user.ID).Order("id").Find(&links).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "ID\tREPO\tTOKEN\tVIEWS\tEXPIRES\tSTATUS")
+	for _, link := range links {
+		views := fmt.Sprint(link.ViewCount)
+		if link.MaxViews > 0 {
+			views += fmt.Sprintf("/%d", link.MaxViews)
+		}
+
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\n", link.PublicID, link.RepoName, link.Token, views, link.ExpiresAt.Format(time.DateTime), linkStatus(&link, now))
+	}
+
+	return out.Flush()
+}
+
+func linkStatus(link *models.ViewerLink, now time.Time) string {
+	switch {
+	case link.DeletedAt.Valid:
+		return "deleted"
+	case !link.ExpiresAt.After(now):
+		return "expired"
+	case link.MaxViews > 0 && link.ViewCount >= link.MaxViews:
+		return "view limit reached"
+	default:
+		return "active"
+	}
+}
+
+func revokeLinks(args []string) error {
+	flags := newFlags("revoke")
+	ref := flags.String("user", "", "GitHub username or user ID")
+	publicID := flags.String("link", "", "ID of the link to revoke")
+	all := flags.Bool("all", false, "revoke every link of the user")
+	if err := flags.connect(args); err != nil {
+		return err
+	}
+
+	if (*publicID == "") == !*all {
+		return errors.New("pass either -link <id> or -all")
+	}
+
+	user, err := findUser(*ref)
+	if err != nil {
+		return err
+	}
+
+	query := db.Where("user_id = ?", user.ID)
+	if !*all {
+		query = query.Where("public_id = ?", *publicID)
+	}
+
+	var links []models.ViewerLink
+	if err := query.Find(&links).Error; err != nil {
+		return err
+	}
+	if len(links) == 0 && !*all {
+		return fmt.Errorf("%s has no link %q", user.GitHubUsername, *publicID)
+	}
+
+	for i := range links {
+		link := &links[i]
+
+		// To delete the link as its owner would, and cut off anyone viewing it now
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(link).Error; err != nil {
+				return err
+			}
+
+			return tx.Where("viewer_link_id = ?", link.ID).Delete(&models.ViewerSession{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("revoking %s: %w", link.PublicID, err)
+		}
+
+		if _, err := webhooks.Enqueue(db, time.Now(), link.UserID, webhooks.EventLinkDeleted, webhooks.Link(link)); err != nil {
+			log.Printf("❌ Could not queue link.deleted webhook for %s: %v", link.PublicID, err)
+		}
+
+		fmt.Printf("Revoked %s (%s)\n", link.PublicID, link.RepoName)
+	}
+
+	fmt.Printf("Revoked %d links of %s ✅\n", len(links), user.GitHubUsername)
+	return nil
+}
+
+func expireLink(args []string) error {
+	flags := newFlags("expire")
+	if err := flags.connect(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: privycode expire <token>")
+	}
+	token := flags.Arg(0)
+
+	now := time.Now()
+	result := db.Model(&models.ViewerLink{}).
+		Where("token = ? AND expires_at > ?", token, now).
+		UpdateColumn("expires_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no active link with token %q", token)
+	}
+
+	// To end its viewer sessions and send link.expired now rather than on the next job run
+	if err := jobs.MarkExpiredLinks(db, now, log.Default()); err != nil {
+		return err
+	}
+
+	fmt.Println("Link expired ✅")
+	return nil
+}
+
+func purgeExpired(args []string) error {
+	flags := newFlags("purge-expired")
+	olderThan := flags.Duration("older-than", 0, "only purge links that expired at least this long ago")
+	dryRun := flags.Bool("dry-run", false, "report what would be purged without deleting")
+	if err := flags.connect(args); err != nil {
+		return err
+	}
+
+	var ids []uint
+	err := db.Unscoped().Model(&models.ViewerLink{}).
+		Where("expires_at <= ?", time.Now().Add(-*olderThan)).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("Would purge %d expired links\n", len(ids))
+		return nil
+	}
+
+	const batch = 500
+	for start := 0; start < len(ids); start += batch {
+		end := min(start+batch, len(ids))
+		if err := jobs.PurgeLinks(db, ids[start:end]); err != nil {
+			return fmt.Errorf("purged %d of %d links: %w", start, len(ids), err)
+		}
+	}
+
+	fmt.Printf("Purged %d expired links ✅\n", len(ids))
+	return nil
+}
@@ -0,0 +1,148 @@
+// Command privycode is the operator CLI. It works directly against the
+// database, so it needs DATABASE_URL (or -database-url) and the
+// TOKEN_ENCRYPTION_* keys, since users and webhooks are decrypted as they are
+// loaded. Run it with no arguments for the list of commands.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/secrets"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"gorm.io/gorm"
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+// cfg is the server config, loaded before any command runs
+var cfg *config.Config
+
+// db is the database the command works on, opened by connect
+var db *gorm.DB
+
+var commands = []command{
+	{"users", "", "List users and how many links they have", listUsers},
+	{"links", "-user <user> [-deleted]", "List a user's links", listLinks},
+	{"revoke", "-user <user> (-link <id> | -all)", "Delete one or all of a user's links and end their viewer sessions", revokeLinks},
+	{"expire", "<token>", "Expire a link now", expireLink},
+	{"purge-expired", "[-older-than 720h] [-dry-run]", "Permanently delete links that expired before the cutoff", purgeExpired},
+	{"reencrypt", "", "Re-encrypt GitHub tokens and webhook secrets under the active key", reencrypt},
+	{"export", "-user <user> [-o file]", "Export everything stored about a user as JSON", exportUser},
+	{"config", "", "Print the configuration (secrets masked) and check it works", diagnose},
+}
+
+func main() {
+	log.SetFlags(0)
+
+	// To read the same settings as the server. Commands only need the
+	// database and keys, so other problems are left to `privycode config`.
+	cfg, _ = config.Load(nil)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+			return
+		}
+	}
+
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: privycode <command> [-database-url url] [flags]")
+	fmt.Fprintln(os.Stderr)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+		if cmd.args != "" {
+			fmt.Fprintf(os.Stderr, "  %-14s   %s %s\n", "", cmd.name, cmd.args)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "<user> is a GitHub username or a user ID.")
+}
+
+// dbFlags are the flags of a command that needs the database
+type dbFlags struct {
+	*flag.FlagSet
+	databaseURL *string
+}
+
+func newFlags(name string) dbFlags {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return dbFlags{
+		FlagSet:     fs,
+		databaseURL: fs.String("database-url", "", "database to use (default $DATABASE_URL)"),
+	}
+}
+
+// To parse the flags, open the chosen database as db and load the
+// encryption keys
+func (f dbFlags) connect(args []string) error {
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+
+	keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+	if err != nil {
+		return fmt.Errorf("loading encryption keys: %w", err)
+	}
+
+	dsn := *f.databaseURL
+	if dsn == "" {
+		dsn = cfg.DatabaseURL
+	}
+	if dsn == "" {
+		return errors.New("no database: set DATABASE_URL or pass -database-url")
+	}
+
+	database, err := config.OpenDB(dsn)
+	if err != nil {
+		return fmt.Errorf("connecting to the database: %w", err)
+	}
+
+	db = models.WithKeyring(database, keyring)
+	return nil
+}
+
+// To find a user by GitHub username or ID
+func findUser(ref string) (*models.User, error) {
+	if ref == "" {
+		return nil, errors.New("-user is required")
+	}
+
+	users := store.NewGormUserStore(db)
+
+	var user *models.User
+	var err error
+	if id, parseErr := strconv.ParseUint(ref, 10, 64); parseErr == nil {
+		user, err = users.ByID(uint(id))
+	} else {
+		user, err = users.ByGitHubUsername(strings.TrimPrefix(ref, "@"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("user %q: %w", ref, err)
+	}
+
+	return user, nil
+}
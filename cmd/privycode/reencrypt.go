@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// To rewrite everything encrypted under an older key. Run it after adding a
+// new key to TOKEN_ENCRYPTION_KEYS; the old key can be removed once it
+// reports zero remaining rows.
+func reencrypt(args []string) error {
+	if err := newFlags("reencrypt").connect(args); err != nil {
+		return err
+	}
+
+	// To pick up any rows still in the old plaintext column
+	if err := config.EncryptLegacyTokens(db); err != nil {
+		return fmt.Errorf("encrypting legacy GitHub tokens: %w", err)
+	}
+
+	tokens, err := config.ReencryptTokens(db)
+	if err != nil {
+		return fmt.Errorf("re-encryption stopped after %d GitHub tokens: %w", tokens, err)
+	}
+
+	webhookSecrets, err := config.ReencryptWebhookSecrets(db)
+	if err != nil {
+		return fmt.Errorf("re-encryption stopped after %d webhook secrets: %w", webhookSecrets, err)
+	}
+
+	keyring, err := models.Keyring(db)
+	if err != nil {
+		return err
+	}
+	activeKeyID := keyring.ActiveKeyID()
+
+	var remainingTokens, remainingSecrets int64
+	db.Model(&models.User{}).
+		Where("git_hub_token_ciphertext <> '' AND git_hub_token_key_id <> ?", activeKeyID).
+		Count(&remainingTokens)
+	db.Model(&models.Webhook{}).
+		Where("secret_ciphertext <> '' AND secret_key_id <> ?", activeKeyID).
+		Count(&remainingSecrets)
+
+	fmt.Printf("Re-encrypted %d GitHub tokens and %d webhook secrets under key %q, %d rows remaining ✅\n",
+		tokens, webhookSecrets, activeKeyID, remainingTokens+remainingSecrets)
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"gorm.io/gorm"
+)
+
+func listUsers(args []string) error {
+	if err := newFlags("users").connect(args); err != nil {
+		return err
+	}
+
+	var users []models.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+
+	var counts []struct {
+		UserID uint
+		Links  int
+	}
+	err := db.Model(&models.ViewerLink{}).
+		Select("user_id, count(*) AS links").
+		Group("user_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	links := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		links[count.UserID] = count.Links
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "ID\tUSERNAME\tEMAIL\tLINKS\tCREATED")
+	for _, user := range users {
+		fmt.Fprintf(out, "%d\t%s\t%s\t%d\t%s\n", user.ID, user.GitHubUsername, user.Email, links[user.ID], user.CreatedAt.Format(time.DateOnly))
+	}
+
+	return out.Flush()
+}
+
+func exportUser(args []string) error {
+	flags := newFlags("export")
+	ref := flags.String("user", "", "GitHub username or user ID")
+	output := flags.String("o", "", "file to write (default stdout)")
+	if err := flags.connect(args); err != nil {
+		return err
+	}
+
+	user, err := findUser(*ref)
+	if err != nil {
+		return err
+	}
+
+	var links []models.ViewerLink
+	if err := db.Unscoped().Where("user_id = ?", user.ID).Order("id").Find(&links).Error; err != nil {
+		return err
+	}
+
+	linkIDs := make(map[uint]string, len(links))
+	ids := make([]uint, 0, len(links))
+	for _, link := range links {
+		linkIDs[link.ID] = link.PublicID
+		ids = append(ids, link.ID)
+	}
+
+	var events []models.ViewEvent
+	if len(ids) > 0 {
+		if err := db.Where("viewer_link_id IN ?", ids).Order("id").Find(&events).Error; err != nil {
+			return err
+		}
+	}
+
+	export := api.UserExport{
+		ExportedAt:    time.Now().UTC(),
+		User:          api.NewUser(user),
+		CreatedAt:     user.CreatedAt,
+		Notifications: api.NewNotificationPreferences(user),
+		Links:         api.NewLinks(links, cfg.FrontendURL),
+		ViewEvents:    api.NewViewEvents(events, linkIDs),
+	}
+
+	var webhook models.Webhook
+	err = db.Where("user_id = ?", user.ID).First(&webhook).Error
+	if err == nil {
+		described := api.NewWebhook(&webhook)
+		export.Webhook = &described
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %s: %d links, %d view events ✅\n", user.GitHubUsername, len(links), len(events))
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/greatdaveo/privycode-server/config"
+)
+
+// To run `server config`: print the settings the server would use, with
+// secrets masked, and exit non-zero if they are invalid
+func runConfig(args []string) {
+	cfg, err := config.Load(args)
+	if cfg == nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	cfg.Print(os.Stdout)
+
+	if err != nil {
+		fmt.Printf("\n❌ Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\nConfiguration is valid ✅")
+}
+
+// To load the settings for a subcommand that only needs some of them. Other
+// problems are reported but do not stop it.
+func loadSubcommandConfig() *config.Config {
+	cfg, err := config.Load(nil)
+	if cfg == nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	if err != nil {
+		log.Printf("⚠️ Configuration problems:\n%v", err)
+	}
+
+	return cfg
+}
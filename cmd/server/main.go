@@ -1,38 +1,95 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/handlers"
+	"github.com/greatdaveo/privycode-server/internal/jobs"
+	"github.com/greatdaveo/privycode-server/internal/mailer"
 	"github.com/greatdaveo/privycode-server/internal/middleware"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/notify"
 	"github.com/greatdaveo/privycode-server/internal/routes"
+	"github.com/greatdaveo/privycode-server/internal/secrets"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
+	"github.com/greatdaveo/privycode-server/migrations"
 )
 
+const webhookPollInterval = 10 * time.Second
+
 func main() {
 
-	// To Connect to the DB
-	config.ConnectDB()
+	// To manage the schema instead of serving: server migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
 
-	// For auto migrate to DB
-	// config.RunMigrations()
+	// To print the settings with secrets masked and check them: server config
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfig(os.Args[2:])
+		return
+	}
 
-	// To set up HTTP router
-	mux := http.NewServeMux()
+	// To read and check every setting before doing anything with them
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("❌ Invalid configuration:\n%v", err)
+	}
 
-	routes.APIRoutes(mux)
+	// To load the keys that encrypt stored GitHub tokens
+	keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+	if err != nil {
+		log.Fatalf("❌ Error loading token encryption keys: %v", err)
+	}
 
-	handlerWithCORS := middleware.WithCORS(mux)
+	// To Connect to the DB, with the keyring the models encrypt with
+	db := models.WithKeyring(config.ConnectDB(cfg), keyring)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// To refuse to serve a schema that is behind; run `server migrate up` first
+	if err := migrations.Check(db); err != nil {
+		log.Fatalf("❌ Database schema is not current: %v", err)
 	}
 
-	log.Printf("Server starting on :%s... ✅", port)
+	// To give the handlers the database, their settings and the GitHub API
+	app := handlers.NewApp(db, cfg)
+
+	// To pick how email is sent
+	mail, err := mailer.New(cfg.Mail, app.Logger)
+	if err != nil {
+		log.Fatalf("❌ Error configuring mailer: %v", err)
+	}
+	app.Mailer = mail
+
+	// To email owners about their links
+	app.Notifier = notify.New(db, mail, cfg.FrontendURL, app.Logger)
+
+	// To send queued webhook deliveries in the background
+	app.Webhooks = webhooks.NewDispatcher(db, app.Clock, app.Logger)
+	go app.Webhooks.Run(context.Background(), webhookPollInterval)
+
+	// To expire, purge and remind in the background; one replica runs each job per interval
+	scheduler := jobs.NewScheduler(db, app.Clock, app.Logger, jobs.Maintenance(db, app.Notifier, jobs.Retention{
+		DeletedLinks: cfg.LinkRetention,
+		ViewEvents:   cfg.ViewEventRetention,
+	}, app.Clock, app.Logger)...)
+	scheduler.Start(context.Background())
+
+	// To set up HTTP router
+	mux := http.NewServeMux()
+
+	routes.APIRoutes(mux, app)
+
+	handlerWithCORS := middleware.WithCORS(mux, cfg.AllowedOrigins())
+
+	log.Printf("Server starting on :%s... ✅", cfg.Port)
 
-	err := http.ListenAndServe(":"+port, handlerWithCORS)
+	err = http.ListenAndServe(":"+cfg.Port, handlerWithCORS)
 
 	if err != nil {
 		log.Fatalf("❌ Could not start sever: %v", err)
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/secrets"
+	"github.com/greatdaveo/privycode-server/migrations"
+)
+
+const migrateUsage = "usage: server migrate up | down [steps] | status"
+
+// To run `server migrate up|down|status` against DATABASE_URL
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	cfg := loadSubcommandConfig()
+	db := config.ConnectDB(cfg)
+
+	switch args[0] {
+	case "up":
+		// To let the legacy token step encrypt any plaintext tokens it finds
+		keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+		if err != nil {
+			log.Fatalf("❌ Error loading token encryption keys: %v", err)
+		}
+
+		config.RunMigrations(models.WithKeyring(db, keyring))
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				log.Fatal(migrateUsage)
+			}
+			steps = n
+		}
+
+		if err := migrations.Down(db, steps); err != nil {
+			log.Fatalf("❌ Error rolling back migrations: %v", err)
+		}
+
+		fmt.Printf("Rolled back %d migration(s) ✅\n", steps)
+
+	case "status":
+		status, err := migrations.Current(db)
+		if err != nil {
+			log.Fatalf("❌ Error reading migration status: %v", err)
+		}
+
+		fmt.Printf("version: %d (latest %d)\n", status.Version, status.Latest)
+		if status.Dirty {
+			fmt.Println("dirty: the last migration failed and must be fixed by hand ❌")
+		}
+		for _, version := range status.Pending {
+			fmt.Printf("pending: %06d\n", version)
+		}
+		if status.Current() {
+			fmt.Println("Schema is up to date ✅")
+		}
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
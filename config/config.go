@@ -3,53 +3,193 @@ package config
 import (
 	"fmt"
 	"log"
-	"os"
 
+	"github.com/google/uuid"
 	"github.com/greatdaveo/privycode-server/internal/models"
-	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/migrations"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
+// To connect to the database in cfg, exiting if it cannot be reached
+func ConnectDB(cfg *Config) *gorm.DB {
 
-func ConnectDB() {
+	database, err := OpenDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("❌ Error connecting to DB: %v", err)
+	}
 
-	// To only load .env file in local development
-	if os.Getenv("GO_ENV") != "production" {
-		err := godotenv.Load()
+	fmt.Printf("Connected to %s successfully!!! ✅\n", database.Dialector.Name())
 
-		if err != nil {
-			log.Fatal("❌ Error loading .env file")
-		}
-	}
+	return database
+}
 
-	dsn := os.Getenv("DATABASE_URL")
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// To open and ping the database at dsn, PostgreSQL or SQLite (see store.Open)
+func OpenDB(dsn string) (*gorm.DB, error) {
+	database, err := store.Open(dsn)
 	if err != nil {
-		log.Fatalf("❌ Error connecting to DB: %v", err)
+		return nil, err
 	}
 
 	// To verify the connection pinging
 	sqlDB, err := database.DB()
 	if err != nil {
-		log.Fatalf("❌ Error getting raw DB handle: %v", err)
+		return nil, err
 	}
 
 	if err := sqlDB.Ping(); err != nil {
-		log.Fatalf("❌ Error pinging DB: %v", err)
+		return nil, err
 	}
 
-	DB = database
+	return database, nil
+}
 
-	fmt.Println("Connected to PostgreSQL successfully!!! ✅")
+// To apply pending schema migrations, then the data fixes that need Go
+func RunMigrations(db *gorm.DB) {
 
-}
+	if err := migrations.Up(db); err != nil {
+		log.Fatalf("❌ Error migrating the database: %v", err)
+	}
 
-func RunMigrations() {
+	if err := EncryptLegacyTokens(db); err != nil {
+		log.Fatalf("❌ Error encrypting legacy GitHub tokens: %v", err)
+	}
 
-	DB.AutoMigrate(&models.User{}, &models.ViewerLink{})
+	if err := BackfillLinkPublicIDs(db); err != nil {
+		log.Fatalf("❌ Error assigning public IDs to viewer links: %v", err)
+	}
 
 	fmt.Println("Migrations completed successfully ✅")
 
 }
+
+// To move plaintext tokens from the old users.git_hub_token column into the
+// encrypted columns, then drop the plaintext column
+func EncryptLegacyTokens(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.User{}, "git_hub_token") {
+		return nil
+	}
+
+	var rows []struct {
+		ID          uint
+		GitHubToken string `gorm:"column:git_hub_token"`
+	}
+	if err := db.Table("users").Select("id, git_hub_token").Where("git_hub_token <> ''").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return migrator.DropColumn(&models.User{}, "git_hub_token")
+	}
+
+	keyring, err := models.Keyring(db)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		ciphertext, keyID, err := keyring.Encrypt(row.GitHubToken)
+		if err != nil {
+			return err
+		}
+
+		err = db.Table("users").Where("id = ?", row.ID).UpdateColumns(map[string]any{
+			"git_hub_token_ciphertext": ciphertext,
+			"git_hub_token_key_id":     keyID,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Encrypted %d legacy GitHub tokens 🔐\n", len(rows))
+
+	return migrator.DropColumn(&models.User{}, "git_hub_token")
+}
+
+// To give public IDs to viewer links created before they existed
+func BackfillLinkPublicIDs(db *gorm.DB) error {
+	var ids []uint
+	if err := db.Unscoped().Model(&models.ViewerLink{}).Where("public_id IS NULL OR public_id = ''").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := db.Unscoped().Model(&models.ViewerLink{}).Where("id = ?", id).UpdateColumn("public_id", uuid.NewString()).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ids) > 0 {
+		fmt.Printf("Assigned public IDs to %d viewer links 🔗\n", len(ids))
+	}
+
+	return nil
+}
+
+// To re-encrypt every stored GitHub token under the active key. Returns the number of rows rewritten.
+func ReencryptTokens(db *gorm.DB) (int, error) {
+	keyring, err := models.Keyring(db)
+	if err != nil {
+		return 0, err
+	}
+
+	activeKeyID := keyring.ActiveKeyID()
+	rewritten := 0
+
+	var users []models.User
+	result := db.Where("git_hub_token_ciphertext <> '' AND git_hub_token_key_id <> ?", activeKeyID).FindInBatches(&users, 100, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			ciphertext, keyID, err := keyring.Encrypt(user.GitHubToken)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]any{
+				"git_hub_token_ciphertext": ciphertext,
+				"git_hub_token_key_id":     keyID,
+			}).Error
+			if err != nil {
+				return err
+			}
+			rewritten++
+		}
+		return nil
+	})
+
+	return rewritten, result.Error
+}
+
+// To re-encrypt every stored webhook secret under the active key. Returns the number of rows rewritten.
+func ReencryptWebhookSecrets(db *gorm.DB) (int, error) {
+	keyring, err := models.Keyring(db)
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten := 0
+
+	var webhooks []models.Webhook
+	result := db.Where("secret_ciphertext <> '' AND secret_key_id <> ?", keyring.ActiveKeyID()).FindInBatches(&webhooks, 100, func(tx *gorm.DB, batch int) error {
+		for _, webhook := range webhooks {
+			ciphertext, keyID, err := keyring.Encrypt(webhook.Secret)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&models.Webhook{}).Where("id = ?", webhook.ID).UpdateColumns(map[string]any{
+				"secret_ciphertext": ciphertext,
+				"secret_key_id":     keyID,
+			}).Error
+			if err != nil {
+				return err
+			}
+			rewritten++
+		}
+		return nil
+	})
+
+	return rewritten, result.Error
+}
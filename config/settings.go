@@ -0,0 +1,378 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/mailer"
+	"github.com/greatdaveo/privycode-server/internal/secrets"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/joho/godotenv"
+)
+
+const defaultFrontendURL = "http://localhost:5173"
+
+// Config is every setting the server reads, loaded once at startup. The
+// comments name the environment variable behind each field.
+type Config struct {
+	Env         string // GO_ENV: "production", or anything else for development
+	Port        string // PORT
+	DatabaseURL string // DATABASE_URL
+	FrontendURL string // FRONTEND_URL, without a trailing slash
+
+	// GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET, GITHUB_CALLBACK_URL, and the
+	// endpoints from GITHUB_BASE_URL / GITHUB_AUTH_URL / GITHUB_TOKEN_URL / GITHUB_API_URL
+	GitHub github.OAuthApp
+
+	SessionTTL        time.Duration // SESSION_TTL_HOURS
+	ReturnToAllowlist []string      // OAUTH_RETURN_TO_ALLOWLIST, origins besides FrontendURL
+
+	EncryptionKeys      string // TOKEN_ENCRYPTION_KEYS
+	EncryptionActiveKey string // TOKEN_ENCRYPTION_ACTIVE_KEY
+
+	// MAILER, MAIL_DIR, SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, MAIL_FROM
+	Mail mailer.Config
+
+	IPHashSalt         string         // IP_HASH_SALT
+	TrustedProxies     []netip.Prefix // TRUSTED_PROXIES, proxies whose X-Forwarded-For is believed
+	ViewerIdleTimeout  time.Duration  // VIEWER_SESSION_IDLE_TIMEOUT
+	LinkRetention      time.Duration  // LINK_RETENTION_DAYS
+	ViewEventRetention time.Duration  // VIEW_EVENT_RETENTION_DAYS
+}
+
+// To get the settings used when nothing is configured
+func Defaults() *Config {
+	return &Config{
+		Env:                "development",
+		Port:               "8080",
+		FrontendURL:        defaultFrontendURL,
+		GitHub:             github.OAuthApp{Endpoints: github.ResolveEndpoints("", "", "", "")},
+		SessionTTL:         7 * 24 * time.Hour,
+		Mail:               mailer.Config{Kind: "log"},
+		ViewerIdleTimeout:  30 * time.Minute,
+		LinkRetention:      30 * 24 * time.Hour,
+		ViewEventRetention: 365 * 24 * time.Hour,
+	}
+}
+
+// To tell whether this is a production deployment
+func (c *Config) Production() bool {
+	return c.Env == "production"
+}
+
+// To get the frontend origins the API serves: FrontendURL and the extra
+// return-to origins
+func (c *Config) AllowedOrigins() []string {
+	return append([]string{c.FrontendURL}, c.ReturnToAllowlist...)
+}
+
+// To load the config from the .env file, the environment and then args, in
+// increasing priority, and validate it. The config is returned even when it
+// is invalid, so tools that need only part of it can still use it; it is nil
+// only when args cannot be parsed.
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	envFile := flags.String("env-file", ".env", "file to load settings from outside production")
+	port := flags.String("port", "", "port to listen on (overrides PORT)")
+	databaseURL := flags.String("database-url", "", "database to use (overrides DATABASE_URL)")
+	frontendURL := flags.String("frontend-url", "", "frontend origin (overrides FRONTEND_URL)")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// To only load .env file in local development
+	if os.Getenv("GO_ENV") != "production" {
+		if err := godotenv.Load(*envFile); err != nil {
+			log.Printf("⚠️ Could not load %s, using the environment only: %v", *envFile, err)
+		}
+	}
+
+	env := envReader{}
+	c := Defaults()
+
+	c.Env = env.str("GO_ENV", c.Env)
+	c.Port = env.str("PORT", c.Port)
+	c.DatabaseURL = env.str("DATABASE_URL", "")
+	c.FrontendURL = env.str("FRONTEND_URL", c.FrontendURL)
+
+	c.GitHub = github.OAuthApp{
+		ClientID:     env.str("GITHUB_CLIENT_ID", ""),
+		ClientSecret: env.str("GITHUB_CLIENT_SECRET", ""),
+		CallbackURL:  env.str("GITHUB_CALLBACK_URL", ""),
+		Endpoints: github.ResolveEndpoints(
+			env.str("GITHUB_BASE_URL", ""),
+			env.str("GITHUB_AUTH_URL", ""),
+			env.str("GITHUB_TOKEN_URL", ""),
+			env.str("GITHUB_API_URL", ""),
+		),
+	}
+
+	c.SessionTTL = env.count("SESSION_TTL_HOURS", c.SessionTTL, time.Hour)
+	for _, origin := range strings.Split(env.str("OAUTH_RETURN_TO_ALLOWLIST", ""), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			c.ReturnToAllowlist = append(c.ReturnToAllowlist, origin)
+		}
+	}
+
+	c.EncryptionKeys = env.str("TOKEN_ENCRYPTION_KEYS", "")
+	c.EncryptionActiveKey = env.str("TOKEN_ENCRYPTION_ACTIVE_KEY", "")
+
+	c.Mail = mailer.Config{
+		Kind: env.str("MAILER", c.Mail.Kind),
+		Dir:  env.str("MAIL_DIR", ""),
+		SMTP: mailer.SMTPConfig{
+			Host:     env.str("SMTP_HOST", ""),
+			Port:     env.str("SMTP_PORT", ""),
+			Username: env.str("SMTP_USERNAME", ""),
+			Password: env.str("SMTP_PASSWORD", ""),
+			From:     env.str("MAIL_FROM", ""),
+		},
+	}
+
+	c.IPHashSalt = env.str("IP_HASH_SALT", "")
+	c.TrustedProxies = env.prefixes("TRUSTED_PROXIES")
+	c.ViewerIdleTimeout = env.duration("VIEWER_SESSION_IDLE_TIMEOUT", c.ViewerIdleTimeout)
+	c.LinkRetention = env.count("LINK_RETENTION_DAYS", c.LinkRetention, 24*time.Hour)
+	c.ViewEventRetention = env.count("VIEW_EVENT_RETENTION_DAYS", c.ViewEventRetention, 24*time.Hour)
+
+	if *port != "" {
+		c.Port = *port
+	}
+	if *databaseURL != "" {
+		c.DatabaseURL = *databaseURL
+	}
+	if *frontendURL != "" {
+		c.FrontendURL = *frontendURL
+	}
+	c.FrontendURL = strings.TrimRight(c.FrontendURL, "/")
+
+	return c, errors.Join(append(env.errs, c.Validate())...)
+}
+
+// To report every problem with the config at once
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		fail("PORT must be a port number, got %q", c.Port)
+	}
+
+	if c.DatabaseURL == "" {
+		fail("DATABASE_URL is required")
+	}
+
+	if err := checkURL(c.FrontendURL); err != nil {
+		fail("FRONTEND_URL %v", err)
+	}
+	for _, origin := range c.ReturnToAllowlist {
+		if err := checkURL(origin); err != nil {
+			fail("OAUTH_RETURN_TO_ALLOWLIST entry %v", err)
+		}
+	}
+
+	if c.GitHub.ClientID == "" || c.GitHub.ClientSecret == "" {
+		fail("GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET are required")
+	}
+	if c.GitHub.CallbackURL != "" {
+		if err := checkURL(c.GitHub.CallbackURL); err != nil {
+			fail("GITHUB_CALLBACK_URL %v", err)
+		}
+	}
+	for _, endpoint := range []struct{ name, value string }{
+		{"GitHub auth URL", c.GitHub.Endpoints.AuthURL},
+		{"GitHub token URL", c.GitHub.Endpoints.TokenURL},
+		{"GitHub API URL", c.GitHub.Endpoints.APIURL},
+	} {
+		if err := checkURL(endpoint.value); err != nil {
+			fail("%s %v", endpoint.name, err)
+		}
+	}
+
+	if _, err := secrets.ParseKeyring(c.EncryptionKeys, c.EncryptionActiveKey); err != nil {
+		fail("TOKEN_ENCRYPTION_KEYS / TOKEN_ENCRYPTION_ACTIVE_KEY: %v", err)
+	}
+
+	switch c.Mail.Kind {
+	case "", "log", "file":
+		// To keep login codes and link notices out of server logs and local files
+		if c.Production() {
+			fail("MAILER must be smtp in production, got %q", c.Mail.Kind)
+		}
+	case "smtp":
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.From == "" {
+			fail("MAILER=smtp needs SMTP_HOST and MAIL_FROM")
+		}
+	default:
+		fail("MAILER must be smtp, log or file, got %q", c.Mail.Kind)
+	}
+
+	if c.Production() && c.IPHashSalt == "" {
+		fail("IP_HASH_SALT is required in production")
+	}
+
+	return errors.Join(errs...)
+}
+
+// To write the config as environment variables, with secrets masked
+func (c *Config) Print(w io.Writer) error {
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range []struct{ name, value string }{
+		{"GO_ENV", c.Env},
+		{"PORT", c.Port},
+		{"DATABASE_URL", maskURL(c.DatabaseURL)},
+		{"FRONTEND_URL", c.FrontendURL},
+		{"GITHUB_CLIENT_ID", c.GitHub.ClientID},
+		{"GITHUB_CLIENT_SECRET", mask(c.GitHub.ClientSecret)},
+		{"GITHUB_CALLBACK_URL", c.GitHub.CallbackURL},
+		{"GITHUB_AUTH_URL", c.GitHub.Endpoints.AuthURL},
+		{"GITHUB_TOKEN_URL", c.GitHub.Endpoints.TokenURL},
+		{"GITHUB_API_URL", c.GitHub.Endpoints.APIURL},
+		{"SESSION_TTL_HOURS", fmt.Sprint(c.SessionTTL.Hours())},
+		{"OAUTH_RETURN_TO_ALLOWLIST", strings.Join(c.ReturnToAllowlist, ",")},
+		{"TOKEN_ENCRYPTION_KEYS", mask(c.EncryptionKeys)},
+		{"TOKEN_ENCRYPTION_ACTIVE_KEY", c.EncryptionActiveKey},
+		{"MAILER", c.Mail.Kind},
+		{"MAIL_DIR", c.Mail.Dir},
+		{"SMTP_HOST", c.Mail.SMTP.Host},
+		{"SMTP_PORT", c.Mail.SMTP.Port},
+		{"SMTP_USERNAME", c.Mail.SMTP.Username},
+		{"SMTP_PASSWORD", mask(c.Mail.SMTP.Password)},
+		{"MAIL_FROM", c.Mail.SMTP.From},
+		{"IP_HASH_SALT", mask(c.IPHashSalt)},
+		{"TRUSTED_PROXIES", joinPrefixes(c.TrustedProxies)},
+		{"VIEWER_SESSION_IDLE_TIMEOUT", c.ViewerIdleTimeout.String()},
+		{"LINK_RETENTION_DAYS", fmt.Sprint(c.LinkRetention.Hours() / 24)},
+		{"VIEW_EVENT_RETENTION_DAYS", fmt.Sprint(c.ViewEventRetention.Hours() / 24)},
+	} {
+		fmt.Fprintf(out, "%s\t%s\n", s.name, s.value)
+	}
+
+	return out.Flush()
+}
+
+// envReader reads typed environment variables, collecting parse errors
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) str(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// To read a positive whole number of units, e.g. hours or days
+func (e *envReader) count(name string, fallback, unit time.Duration) time.Duration {
+	raw := e.str(name, "")
+	if raw == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a positive whole number, got %q", name, raw))
+		return fallback
+	}
+
+	return time.Duration(n) * unit
+}
+
+// To read a positive Go duration such as "30m"
+func (e *envReader) duration(name string, fallback time.Duration) time.Duration {
+	raw := e.str(name, "")
+	if raw == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a positive duration such as 30m, got %q", name, raw))
+		return fallback
+	}
+
+	return d
+}
+
+// To read a comma-separated list of IP addresses and CIDR ranges
+func (e *envReader) prefixes(name string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(e.str(name, ""), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s entries must be IP addresses or CIDR ranges, got %q", name, entry))
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes
+}
+
+func joinPrefixes(prefixes []netip.Prefix) string {
+	entries := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		entries[i] = prefix.String()
+	}
+
+	return strings.Join(entries, ",")
+}
+
+// To check that value is an absolute http(s) URL
+func checkURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("must be an absolute http(s) URL, got %q", value)
+	}
+
+	return nil
+}
+
+// To show that a secret is set without any of it
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return "********"
+}
+
+// To show which database a URL points at without its password
+func maskURL(value string) string {
+	// To show SQLite paths as they are; they carry no credentials
+	if store.IsSQLite(value) {
+		return value
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return mask(value)
+	}
+
+	return parsed.Redacted()
+}
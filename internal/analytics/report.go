@@ -0,0 +1,237 @@
+// Package analytics turns the view events of a link into the summary shown
+// on the owner's dashboard. It works on plain slices so it does not depend on
+// the database engine.
+package analytics
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// A visit ends after this much inactivity from the same viewer
+const VisitIdleGap = 30 * time.Minute
+
+// How many entries the top files, folders and referrers lists keep
+const topN = 10
+
+type Report struct {
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	TotalViews    int            `json:"total_views"`    // viewer sessions, as counted by the link's view count
+	TotalRequests int            `json:"total_requests"` // every viewer request, including files and folders
+	UniqueViewers int            `json:"unique_viewers"` // by verified email, else hashed IP
+	Sessions      int            `json:"sessions"`       // visits, split by VisitIdleGap
+	FirstViewedAt *time.Time     `json:"first_viewed_at"`
+	LastViewedAt  *time.Time     `json:"last_viewed_at"`
+	Daily         []DailyBucket  `json:"daily"`
+	TopFiles      []PathCount    `json:"top_files"`
+	TopFolders    []PathCount    `json:"top_folders"`
+	TimeOnRepo    TimeOnRepo     `json:"time_on_repo"`
+	Referrers     []ReferrerHits `json:"referrers"`
+}
+
+type DailyBucket struct {
+	Date     string `json:"date"` // YYYY-MM-DD, UTC
+	Views    int    `json:"views"`
+	Requests int    `json:"requests"`
+}
+
+type PathCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+type ReferrerHits struct {
+	Referrer string `json:"referrer"`
+	Sessions int    `json:"sessions"`
+}
+
+// TimeOnRepo estimates reading time as the span between the first and last
+// request of each visit; a single-request visit counts as zero.
+type TimeOnRepo struct {
+	TotalSeconds   int64 `json:"total_seconds"`
+	AverageSeconds int64 `json:"average_seconds"`
+	LongestSeconds int64 `json:"longest_seconds"`
+}
+
+type visit struct {
+	first, last time.Time
+	referrer    string
+}
+
+// To summarise the events of one link between from and to
+func Build(events []models.ViewEvent, from, to time.Time) Report {
+	report := Report{
+		From:       from,
+		To:         to,
+		Daily:      dailyBuckets(from, to),
+		TopFiles:   []PathCount{},
+		TopFolders: []PathCount{},
+		Referrers:  []ReferrerHits{},
+	}
+
+	sorted := make([]models.ViewEvent, len(events))
+	copy(sorted, events)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	dayIndex := map[string]int{}
+	for i, bucket := range report.Daily {
+		dayIndex[bucket.Date] = i
+	}
+
+	viewers := map[string]bool{}
+	viewSessions := map[uint]bool{}
+	files := map[string]int{}
+	folders := map[string]int{}
+	openVisits := map[string]*visit{}
+	var visits []*visit
+
+	for _, event := range sorted {
+		report.TotalRequests++
+		view := startsView(event, viewSessions)
+		if view {
+			report.TotalViews++
+		}
+
+		if i, ok := dayIndex[event.CreatedAt.UTC().Format(time.DateOnly)]; ok {
+			report.Daily[i].Requests++
+			if view {
+				report.Daily[i].Views++
+			}
+		}
+
+		switch event.Endpoint {
+		case models.EndpointFile:
+			files[event.Path]++
+		case models.EndpointFolder:
+			if event.Path != "" {
+				folders[event.Path]++
+			}
+		}
+
+		viewer := viewerKey(event)
+		if viewer != "" {
+			viewers[viewer] = true
+		}
+
+		// To group requests into visits per viewer session (or viewer, for public links)
+		visitKey := viewer
+		if event.ViewerSessionID != nil {
+			visitKey = "session:" + strconv.FormatUint(uint64(*event.ViewerSessionID), 10)
+		}
+
+		current, ok := openVisits[visitKey]
+		if !ok || event.CreatedAt.Sub(current.last) > VisitIdleGap {
+			current = &visit{first: event.CreatedAt, last: event.CreatedAt}
+			openVisits[visitKey] = current
+			visits = append(visits, current)
+		}
+		current.last = event.CreatedAt
+		if current.referrer == "" {
+			current.referrer = event.Referrer
+		}
+	}
+
+	if len(sorted) > 0 {
+		first, last := sorted[0].CreatedAt, sorted[len(sorted)-1].CreatedAt
+		report.FirstViewedAt, report.LastViewedAt = &first, &last
+	}
+
+	report.UniqueViewers = len(viewers)
+	report.Sessions = len(visits)
+	report.TopFiles = topPaths(files)
+	report.TopFolders = topPaths(folders)
+
+	referrers := map[string]int{}
+	for _, v := range visits {
+		seconds := int64(v.last.Sub(v.first).Seconds())
+		report.TimeOnRepo.TotalSeconds += seconds
+		if seconds > report.TimeOnRepo.LongestSeconds {
+			report.TimeOnRepo.LongestSeconds = seconds
+		}
+		if v.referrer != "" {
+			referrers[v.referrer]++
+		}
+	}
+	if len(visits) > 0 {
+		report.TimeOnRepo.AverageSeconds = report.TimeOnRepo.TotalSeconds / int64(len(visits))
+	}
+
+	for referrer, count := range referrers {
+		report.Referrers = append(report.Referrers, ReferrerHits{Referrer: referrer, Sessions: count})
+	}
+	sort.Slice(report.Referrers, func(i, j int) bool {
+		if report.Referrers[i].Sessions != report.Referrers[j].Sessions {
+			return report.Referrers[i].Sessions > report.Referrers[j].Sessions
+		}
+		return report.Referrers[i].Referrer < report.Referrers[j].Referrer
+	})
+	if len(report.Referrers) > topN {
+		report.Referrers = report.Referrers[:topN]
+	}
+
+	return report
+}
+
+// To check whether event is the first of a viewer session, which is what the
+// link's view count counts. Events recorded before viewer sessions existed
+// have none, so each repo open among them counts instead.
+func startsView(event models.ViewEvent, seen map[uint]bool) bool {
+	if event.ViewerSessionID == nil {
+		return event.Endpoint == models.EndpointRepo
+	}
+	if seen[*event.ViewerSessionID] {
+		return false
+	}
+
+	seen[*event.ViewerSessionID] = true
+	return true
+}
+
+// To identify a viewer: the verified email when there is one, else the hashed IP
+func viewerKey(event models.ViewEvent) string {
+	if event.ViewerEmail != "" {
+		return "email:" + event.ViewerEmail
+	}
+	if event.IPHash != "" {
+		return "ip:" + event.IPHash
+	}
+
+	return ""
+}
+
+// To create one empty bucket per UTC day from from to to, inclusive
+func dailyBuckets(from, to time.Time) []DailyBucket {
+	buckets := []DailyBucket{}
+
+	day := time.Date(from.UTC().Year(), from.UTC().Month(), from.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	for !day.After(to.UTC()) {
+		buckets = append(buckets, DailyBucket{Date: day.Format(time.DateOnly)})
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return buckets
+}
+
+func topPaths(counts map[string]int) []PathCount {
+	paths := make([]PathCount, 0, len(counts))
+	for path, count := range counts {
+		paths = append(paths, PathCount{Path: path, Count: count})
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].Count != paths[j].Count {
+			return paths[i].Count > paths[j].Count
+		}
+		return paths[i].Path < paths[j].Path
+	})
+
+	if len(paths) > topN {
+		paths = paths[:topN]
+	}
+
+	return paths
+}
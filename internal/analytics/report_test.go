@@ -0,0 +1,38 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+func TestViewsCountViewerSessions(t *testing.T) {
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	first, second := uint(1), uint(2)
+
+	event := func(session *uint, endpoint string, at time.Duration) models.ViewEvent {
+		return models.ViewEvent{ViewerSessionID: session, Endpoint: endpoint, CreatedAt: day.Add(at)}
+	}
+	events := []models.ViewEvent{
+		// One session opening the repo twice and reading a file counts once
+		event(&first, models.EndpointRepo, time.Hour),
+		event(&first, models.EndpointFile, time.Hour+time.Minute),
+		event(&first, models.EndpointRepo, time.Hour+2*time.Minute),
+		// A session that starts on a file still counts, on the day it started
+		event(&second, models.EndpointFile, 25*time.Hour),
+		event(&second, models.EndpointRepo, 26*time.Hour),
+	}
+
+	report := Build(events, day, day.Add(47*time.Hour))
+
+	if report.TotalViews != 2 {
+		t.Errorf("TotalViews = %d, want 2", report.TotalViews)
+	}
+	if report.TotalRequests != 5 {
+		t.Errorf("TotalRequests = %d, want 5", report.TotalRequests)
+	}
+	if len(report.Daily) != 2 || report.Daily[0].Views != 1 || report.Daily[1].Views != 1 {
+		t.Errorf("Daily = %+v, want one view on each day", report.Daily)
+	}
+}
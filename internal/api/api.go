@@ -0,0 +1,117 @@
+package api
+
+import (
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/analytics"
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// Message is the body of responses that only confirm an action
+type Message struct {
+	Message string `json:"message"`
+}
+
+// User is the logged-in user
+type User struct {
+	GitHubUsername string `json:"github_username"`
+	Email          string `json:"email"`
+}
+
+func NewUser(user *models.User) User {
+	return User{GitHubUsername: user.GitHubUsername, Email: user.Email}
+}
+
+// Session is a PrivyCode session token, handed out once in exchange for a login code
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionsRevoked answers a logout from every device
+type SessionsRevoked struct {
+	Message         string `json:"message"`
+	SessionsRevoked int64  `json:"sessions_revoked"`
+}
+
+// NotificationPreferences are the owner emails a user opted into. Fields are
+// pointers so updates can leave some unchanged.
+type NotificationPreferences struct {
+	FirstOpened  *bool `json:"first_opened"`
+	ExpiringSoon *bool `json:"expiring_soon"`
+	WeeklyDigest *bool `json:"weekly_digest"`
+}
+
+func NewNotificationPreferences(user *models.User) NotificationPreferences {
+	return NotificationPreferences{
+		FirstOpened:  &user.NotifyFirstOpened,
+		ExpiringSoon: &user.NotifyExpiring,
+		WeeklyDigest: &user.WeeklyDigest,
+	}
+}
+
+// LinkUpdated answers a link update with the link as it is now
+type LinkUpdated struct {
+	Message string `json:"message"`
+	Link    Link   `json:"link"`
+}
+
+// LinkAnalytics is the analytics report of one link
+type LinkAnalytics struct {
+	LinkID    string           `json:"link_id"`
+	RepoName  string           `json:"repo_name"`
+	ViewCount int              `json:"view_count"`
+	Analytics analytics.Report `json:"analytics"`
+}
+
+// ContentEntry is a file or folder shown to viewers. GitHub's URLs are left
+// out: for private repos they can carry access tokens.
+type ContentEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	SHA  string `json:"sha"`
+	Size int    `json:"size"`
+	Type string `json:"type"`
+}
+
+func NewContentEntries(entries []github.ContentEntry) []ContentEntry {
+	out := make([]ContentEntry, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, ContentEntry{
+			Name: entry.Name,
+			Path: entry.Path,
+			SHA:  entry.SHA,
+			Size: entry.Size,
+			Type: entry.Type,
+		})
+	}
+
+	return out
+}
+
+// ViewerAccessRequired tells the frontend what a viewer must do before the
+// link reveals anything
+type ViewerAccessRequired struct {
+	PasswordRequired bool `json:"password_required"`
+	EmailRequired    bool `json:"email_required"`
+}
+
+// ViewerInfo describes the shared repo to a viewer who has access
+type ViewerInfo struct {
+	PasswordRequired bool   `json:"password_required"`
+	EmailRequired    bool   `json:"email_required"`
+	ViewerEmail      string `json:"viewer_email"`
+	GitHubUsername   string `json:"github_username"`
+	RepoName         string `json:"repo_name"`
+	Ref              string `json:"ref"`
+	CommitSHA        string `json:"commit_sha"`
+	FollowBranch     bool   `json:"follow_branch"`
+}
+
+// ViewerSession is handed out when a viewer unlocks a link or verifies their email
+type ViewerSession struct {
+	ViewerSession string    `json:"viewer_session"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Email         string    `json:"email,omitempty"` // the verified email, for email-verified links
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// Values that must never appear in anything the server sends
+var sentinels = []string{
+	"SENTINEL-github-token",
+	"SENTINEL-github-token-ciphertext",
+	"SENTINEL-github-token-key",
+	"SENTINEL-password-hash",
+	"SENTINEL-webhook-secret",
+	"SENTINEL-webhook-secret-ciphertext",
+	"SENTINEL-webhook-secret-key",
+	"SENTINEL-ip-hash",
+}
+
+func secretUser() models.User {
+	return models.User{
+		Email:                 "owner@example.com",
+		GitHubUsername:        "owner",
+		GitHubToken:           "SENTINEL-github-token",
+		GitHubTokenCiphertext: "SENTINEL-github-token-ciphertext",
+		GitHubTokenKeyID:      "SENTINEL-github-token-key",
+	}
+}
+
+func secretWebhook() models.Webhook {
+	return models.Webhook{
+		User:             secretUser(),
+		URL:              "https://hooks.example.com/privycode",
+		Events:           []string{"link.created"},
+		Active:           true,
+		Secret:           "SENTINEL-webhook-secret",
+		SecretCiphertext: "SENTINEL-webhook-secret-ciphertext",
+		SecretKeyID:      "SENTINEL-webhook-secret-key",
+	}
+}
+
+func TestDTOsLeaveOutSecrets(t *testing.T) {
+	user := secretUser()
+	webhook := secretWebhook()
+
+	link := models.ViewerLink{
+		PublicID:     "5f0c7a4e-1111-4222-8333-944445555666",
+		RepoName:     "private-repo",
+		User:         secretUser(),
+		Token:        "viewer-token",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		PasswordHash: "SENTINEL-password-hash",
+	}
+
+	delivery := models.WebhookDelivery{
+		Webhook: webhook,
+		UUID:    "delivery-id",
+		Event:   "link.created",
+		Payload: `{"event":"link.created"}`,
+		Status:  models.DeliveryPending,
+	}
+
+	apiWebhook := NewWebhook(&webhook)
+	dtos := map[string]any{
+		"User":            NewUser(&user),
+		"Link":            NewLink(&link, "https://privycode.example.com"),
+		"Webhook":         apiWebhook,
+		"WebhookDelivery": NewWebhookDelivery(&delivery),
+		"UserExport": UserExport{
+			User:          NewUser(&user),
+			Notifications: NewNotificationPreferences(&user),
+			Links:         NewLinks([]models.ViewerLink{link}, "https://privycode.example.com"),
+			ViewEvents:    NewViewEvents([]models.ViewEvent{{ViewerLinkID: link.ID, IPHash: "SENTINEL-ip-hash"}}, nil),
+			Webhook:       &apiWebhook,
+		},
+	}
+
+	for name, dto := range dtos {
+		body, err := json.Marshal(dto)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		for _, sentinel := range sentinels {
+			if strings.Contains(string(body), sentinel) {
+				t.Errorf("%s leaks %s: %s", name, sentinel, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// UserExport is everything stored about a user, as `privycode export` writes it
+type UserExport struct {
+	ExportedAt    time.Time               `json:"exported_at"`
+	User          User                    `json:"user"`
+	CreatedAt     time.Time               `json:"created_at"`
+	Notifications NotificationPreferences `json:"notifications"`
+	Links         []Link                  `json:"links"`
+	ViewEvents    []ViewEvent             `json:"view_events"`
+	Webhook       *Webhook                `json:"webhook"`
+}
+
+// ViewEvent is one recorded viewer request, without the hashed IP
+type ViewEvent struct {
+	LinkID      string    `json:"link_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Endpoint    string    `json:"endpoint"`
+	Path        string    `json:"path"`
+	UserAgent   string    `json:"user_agent"`
+	ViewerEmail string    `json:"viewer_email"`
+	Referrer    string    `json:"referrer"`
+}
+
+// To describe view events; linkIDs maps each link's row ID to its public ID
+func NewViewEvents(events []models.ViewEvent, linkIDs map[uint]string) []ViewEvent {
+	out := make([]ViewEvent, 0, len(events))
+	for _, event := range events {
+		out = append(out, ViewEvent{
+			LinkID:      linkIDs[event.ViewerLinkID],
+			CreatedAt:   event.CreatedAt,
+			Endpoint:    event.Endpoint,
+			Path:        event.Path,
+			UserAgent:   event.UserAgent,
+			ViewerEmail: event.ViewerEmail,
+			Referrer:    event.Referrer,
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,81 @@
+// Package api holds the JSON shapes the server sends to clients. Handlers
+// build these from models instead of encoding models directly, so internal
+// IDs, hashes and tokens stay out of responses.
+package api
+
+import (
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// Link is a viewer link as its owner sees it
+type Link struct {
+	ID        string    `json:"id"`
+	RepoName  string    `json:"repo_name"`
+	Token     string    `json:"token"`
+	ViewerURL string    `json:"viewer_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxViews  int       `json:"max_views"`
+	ViewCount int       `json:"view_count"`
+
+	Ref          string `json:"ref"`
+	CommitSHA    string `json:"commit_sha"`
+	FollowBranch bool   `json:"follow_branch"`
+
+	AllowPaths       []string `json:"allow_paths"`
+	DenyPaths        []string `json:"deny_paths"`
+	DisableRedaction bool     `json:"disable_redaction"`
+
+	PasswordProtected bool     `json:"password_protected"`
+	AccessMode        string   `json:"access_mode"`
+	AllowedEmails     []string `json:"allowed_emails"`
+}
+
+// To describe a link for its owner; viewerBaseURL is where viewers open it
+func NewLink(link *models.ViewerLink, viewerBaseURL string) Link {
+	return Link{
+		ID:        link.PublicID,
+		RepoName:  link.RepoName,
+		Token:     link.Token,
+		ViewerURL: viewerBaseURL + "/view/" + link.Token,
+		CreatedAt: link.CreatedAt,
+		UpdatedAt: link.UpdatedAt,
+		ExpiresAt: link.ExpiresAt,
+		MaxViews:  link.MaxViews,
+		ViewCount: link.ViewCount,
+
+		Ref:          link.Ref,
+		CommitSHA:    link.CommitSHA,
+		FollowBranch: link.FollowBranch,
+
+		AllowPaths:       nonNil(link.AllowPaths),
+		DenyPaths:        nonNil(link.DenyPaths),
+		DisableRedaction: link.DisableRedaction,
+
+		PasswordProtected: link.HasPassword(),
+		AccessMode:        link.AccessMode,
+		AllowedEmails:     nonNil(link.AllowedEmails),
+	}
+}
+
+// To describe several links for their owner
+func NewLinks(links []models.ViewerLink, viewerBaseURL string) []Link {
+	out := make([]Link, 0, len(links))
+	for i := range links {
+		out = append(out, NewLink(&links[i], viewerBaseURL))
+	}
+
+	return out
+}
+
+// To encode empty lists as [] rather than null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// Webhook is a user's webhook configuration, without its secret
+type Webhook struct {
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewWebhook(webhook *models.Webhook) Webhook {
+	return Webhook{
+		URL:       webhook.URL,
+		Events:    nonNil(webhook.Events),
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+// WebhookConfig answers GET /webhook
+type WebhookConfig struct {
+	Webhook         Webhook  `json:"webhook"`
+	AvailableEvents []string `json:"available_events"`
+}
+
+// WebhookSaved answers PUT /webhook. Secret is only set when the webhook was
+// created or its secret rotated; it is never shown again.
+type WebhookSaved struct {
+	Webhook Webhook `json:"webhook"`
+	Secret  string  `json:"secret,omitempty"`
+}
+
+// WebhookDelivery is one entry of the delivery log
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func NewWebhookDelivery(delivery *models.WebhookDelivery) WebhookDelivery {
+	out := WebhookDelivery{
+		ID:             delivery.UUID,
+		Event:          delivery.Event,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		Payload:        json.RawMessage(delivery.Payload),
+	}
+
+	// To only show a next attempt while one is planned
+	if delivery.Status == models.DeliveryPending {
+		next := delivery.NextAttemptAt
+		out.NextAttemptAt = &next
+	}
+
+	return out
+}
+
+func NewWebhookDeliveries(deliveries []models.WebhookDelivery) []WebhookDelivery {
+	out := make([]WebhookDelivery, 0, len(deliveries))
+	for i := range deliveries {
+		out = append(out, NewWebhookDelivery(&deliveries[i]))
+	}
+
+	return out
+}
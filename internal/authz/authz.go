@@ -0,0 +1,87 @@
+// Package authz decides what a logged-in user may do with a resource.
+// Authenticated handlers load resources through it, so a resource the user
+// may not touch is indistinguishable from one that does not exist: both are
+// ErrNotFound, and handlers answer 404 for both.
+package authz
+
+import (
+	"errors"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"gorm.io/gorm"
+)
+
+type Action string
+
+const (
+	Read   Action = "read"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// To decide whether a user may act on a viewer link: only its owner may
+func CanLink(user *models.User, action Action, link *models.ViewerLink) bool {
+	return user != nil && link != nil && link.UserID == user.ID
+}
+
+// To decide whether a user may act on a webhook: only its owner may
+func CanWebhook(user *models.User, action Action, webhook *models.Webhook) bool {
+	return user != nil && webhook != nil && webhook.UserID == user.ID
+}
+
+// To load a viewer link by public ID for an action, or ErrNotFound if it
+// does not exist or the user may not perform the action on it
+func Link(links store.LinkStore, user *models.User, action Action, publicID string) (*models.ViewerLink, error) {
+	if user == nil || publicID == "" {
+		return nil, ErrNotFound
+	}
+
+	link, err := links.ByPublicID(user.ID, publicID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if !CanLink(user, action, link) {
+		return nil, ErrNotFound
+	}
+
+	return link, nil
+}
+
+// To list the viewer links the user may read
+func Links(links store.LinkStore, user *models.User) ([]models.ViewerLink, error) {
+	if user == nil {
+		return nil, ErrNotFound
+	}
+
+	return links.ForUser(user.ID)
+}
+
+// To load the user's webhook for an action, or ErrNotFound if they have none
+func Webhook(db *gorm.DB, user *models.User, action Action) (*models.Webhook, error) {
+	if user == nil {
+		return nil, ErrNotFound
+	}
+
+	var webhook models.Webhook
+	if err := db.Where("user_id = ?", user.ID).First(&webhook).Error; err != nil {
+		return nil, notFound(err)
+	}
+
+	if !CanWebhook(user, action, &webhook) {
+		return nil, ErrNotFound
+	}
+
+	return &webhook, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, store.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
+}
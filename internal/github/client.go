@@ -0,0 +1,237 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultAPIBaseURL = "https://api.github.com"
+
+// Client is the subset of the GitHub REST API that PrivyCode uses.
+// Every call takes the access token of the user whose repositories are read.
+type Client interface {
+	GetUser(ctx context.Context, token string) (*User, error)
+	GetRepo(ctx context.Context, token, owner, repo string) (*Repo, error)
+	ResolveRef(ctx context.Context, token, owner, repo, ref string) (string, error)
+	ListContents(ctx context.Context, token, owner, repo, path, ref string) ([]ContentEntry, error)
+	GetFileRaw(ctx context.Context, token, owner, repo, path, ref string) ([]byte, error)
+	GetTree(ctx context.Context, token, owner, repo, ref string, recursive bool) (*Tree, error)
+}
+
+type User struct {
+	Login string `json:"login"`
+	Email string `json:"email"`
+}
+
+type Repo struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// ContentEntry is one item returned by the contents API
+type ContentEntry struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	SHA         string `json:"sha"`
+	Size        int    `json:"size"`
+	Type        string `json:"type"`
+	URL         string `json:"url"`
+	HTMLURL     string `json:"html_url"`
+	GitURL      string `json:"git_url"`
+	DownloadURL string `json:"download_url"`
+}
+
+type TreeEntry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+	Size int    `json:"size"`
+}
+
+type Tree struct {
+	SHA       string      `json:"sha"`
+	Entries   []TreeEntry `json:"tree"`
+	Truncated bool        `json:"truncated"`
+}
+
+// APIError is returned when GitHub answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github: %d %s", e.StatusCode, e.Body)
+}
+
+// To check whether err is a GitHub 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// HTTPClient talks to the GitHub REST API over HTTP
+type HTTPClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// To create a client for the given API base URL (api.github.com when empty)
+func NewClient(baseURL string) *HTTPClient {
+	if baseURL == "" {
+		baseURL = DefaultAPIBaseURL
+	}
+
+	return &HTTPClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (c *HTTPClient) GetUser(ctx context.Context, token string) (*User, error) {
+	var user User
+	if err := c.getJSON(ctx, token, "/user", nil, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (c *HTTPClient) GetRepo(ctx context.Context, token, owner, repo string) (*Repo, error) {
+	var result Repo
+	if err := c.getJSON(ctx, token, repoPath(owner, repo), nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// To resolve a branch, tag or (short) SHA to the full commit SHA
+func (c *HTTPClient) ResolveRef(ctx context.Context, token, owner, repo, ref string) (string, error) {
+	body, err := c.get(ctx, token, repoPath(owner, repo)+"/commits/"+url.PathEscape(ref), nil, "application/vnd.github.sha")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
+
+func (c *HTTPClient) ListContents(ctx context.Context, token, owner, repo, path, ref string) ([]ContentEntry, error) {
+	body, err := c.get(ctx, token, repoPath(owner, repo)+"/contents"+escapePath(path), refQuery(ref), "application/vnd.github.v3+json")
+	if err != nil {
+		return nil, err
+	}
+
+	// To handle a path that points at a single file, which GitHub returns as an object
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "{") {
+		var entry ContentEntry
+		if err := json.Unmarshal(body, &entry); err != nil {
+			return nil, err
+		}
+		return []ContentEntry{entry}, nil
+	}
+
+	var entries []ContentEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (c *HTTPClient) GetFileRaw(ctx context.Context, token, owner, repo, path, ref string) ([]byte, error) {
+	return c.get(ctx, token, repoPath(owner, repo)+"/contents"+escapePath(path), refQuery(ref), "application/vnd.github.v3.raw")
+}
+
+func (c *HTTPClient) GetTree(ctx context.Context, token, owner, repo, ref string, recursive bool) (*Tree, error) {
+	query := url.Values{}
+	if recursive {
+		query.Set("recursive", "1")
+	}
+
+	var tree Tree
+	if err := c.getJSON(ctx, token, repoPath(owner, repo)+"/git/trees/"+url.PathEscape(ref), query, &tree); err != nil {
+		return nil, err
+	}
+
+	return &tree, nil
+}
+
+func (c *HTTPClient) getJSON(ctx context.Context, token, path string, query url.Values, out any) error {
+	body, err := c.get(ctx, token, path, query, "application/vnd.github.v3+json")
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+func (c *HTTPClient) get(ctx context.Context, token, path string, query url.Values, accept string) ([]byte, error) {
+	apiURL := c.BaseURL + path
+	if len(query) > 0 {
+		apiURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "privycode-server")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return body, nil
+}
+
+func repoPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// To escape each segment of a repository path, keeping the slashes
+func escapePath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+func refQuery(ref string) url.Values {
+	if ref == "" {
+		return nil
+	}
+
+	return url.Values{"ref": {ref}}
+}
@@ -0,0 +1,218 @@
+package github
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeClient is an in-memory Client for tests and local development.
+// Repositories are flat maps of file path to content; directories are
+// derived from the file paths. Refs are ignored.
+//
+// Symlinks map a link path to the file it points at. Like GitHub, listings
+// show them as "symlink" entries and GetFileRaw serves the target's content.
+type FakeClient struct {
+	mu    sync.RWMutex
+	users map[string]User
+	repos map[string]*FakeRepo
+}
+
+type FakeRepo struct {
+	Repo     Repo
+	Files    map[string]string
+	Symlinks map[string]string
+}
+
+// To check that FakeClient keeps up with the interface
+var _ Client = (*FakeClient)(nil)
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		users: map[string]User{},
+		repos: map[string]*FakeRepo{},
+	}
+}
+
+// To register the GitHub user returned for an access token
+func (f *FakeClient) AddUser(token string, user User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.users[token] = user
+}
+
+// To register a repository with its files
+func (f *FakeClient) AddRepo(owner, name string, files map[string]string) *FakeRepo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo := &FakeRepo{
+		Repo: Repo{
+			Name:          name,
+			FullName:      owner + "/" + name,
+			Private:       true,
+			DefaultBranch: "main",
+		},
+		Files: files,
+	}
+	f.repos[owner+"/"+name] = repo
+
+	return repo
+}
+
+func (f *FakeClient) GetUser(ctx context.Context, token string) (*User, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	user, ok := f.users[token]
+	if !ok {
+		return nil, &APIError{StatusCode: http.StatusUnauthorized, Body: `{"message":"Bad credentials"}`}
+	}
+
+	return &user, nil
+}
+
+func (f *FakeClient) GetRepo(ctx context.Context, token, owner, repo string) (*Repo, error) {
+	r, err := f.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := r.Repo
+	return &result, nil
+}
+
+// To resolve any ref to a stable fake SHA derived from its name
+func (f *FakeClient) ResolveRef(ctx context.Context, token, owner, repo, ref string) (string, error) {
+	if _, err := f.repo(owner, repo); err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum([]byte(owner + "/" + repo + "@" + ref))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (f *FakeClient) ListContents(ctx context.Context, token, owner, repo, dir, ref string) ([]ContentEntry, error) {
+	r, err := f.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	dir = strings.Trim(dir, "/")
+	if _, ok := r.Files[dir]; ok {
+		return []ContentEntry{fileEntry(dir, r.Files[dir])}, nil
+	}
+
+	leaves := map[string]ContentEntry{}
+	for filePath, content := range r.Files {
+		leaves[filePath] = fileEntry(filePath, content)
+	}
+	for linkPath, target := range r.Symlinks {
+		leaves[linkPath] = ContentEntry{Name: path.Base(linkPath), Path: linkPath, Size: len(target), Type: "symlink"}
+	}
+
+	seen := map[string]bool{}
+	var entries []ContentEntry
+	for leafPath, leaf := range leaves {
+		rest := leafPath
+		if dir != "" {
+			if !strings.HasPrefix(leafPath, dir+"/") {
+				continue
+			}
+			rest = strings.TrimPrefix(leafPath, dir+"/")
+		}
+
+		name, _, isDir := strings.Cut(rest, "/")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if isDir {
+			entries = append(entries, ContentEntry{Name: name, Path: path.Join(dir, name), Type: "dir"})
+		} else {
+			entries = append(entries, leaf)
+		}
+	}
+
+	if len(entries) == 0 && dir != "" {
+		return nil, notFound()
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+func (f *FakeClient) GetFileRaw(ctx context.Context, token, owner, repo, filePath, ref string) ([]byte, error) {
+	r, err := f.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath = strings.Trim(filePath, "/")
+	if target, ok := r.Symlinks[filePath]; ok {
+		filePath = target
+	}
+
+	content, ok := r.Files[filePath]
+	if !ok {
+		return nil, notFound()
+	}
+
+	return []byte(content), nil
+}
+
+func (f *FakeClient) GetTree(ctx context.Context, token, owner, repo, ref string, recursive bool) (*Tree, error) {
+	r, err := f.repo(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &Tree{SHA: ref}
+	dirs := map[string]bool{}
+	for filePath, content := range r.Files {
+		if !recursive && strings.Contains(filePath, "/") {
+			dir, _, _ := strings.Cut(filePath, "/")
+			dirs[dir] = true
+			continue
+		}
+
+		for dir := path.Dir(filePath); recursive && dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+		tree.Entries = append(tree.Entries, TreeEntry{Path: filePath, Mode: "100644", Type: "blob", Size: len(content)})
+	}
+
+	for dir := range dirs {
+		tree.Entries = append(tree.Entries, TreeEntry{Path: dir, Mode: "040000", Type: "tree"})
+	}
+
+	sort.Slice(tree.Entries, func(i, j int) bool { return tree.Entries[i].Path < tree.Entries[j].Path })
+	return tree, nil
+}
+
+func (f *FakeClient) repo(owner, name string) (*FakeRepo, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	r, ok := f.repos[owner+"/"+name]
+	if !ok {
+		return nil, notFound()
+	}
+
+	return r, nil
+}
+
+func fileEntry(filePath, content string) ContentEntry {
+	return ContentEntry{Name: path.Base(filePath), Path: filePath, Size: len(content), Type: "file"}
+}
+
+func notFound() error {
+	return &APIError{StatusCode: http.StatusNotFound, Body: `{"message":"Not Found"}`}
+}
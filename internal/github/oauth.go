@@ -2,30 +2,91 @@ package github
 
 import (
 	"context"
-	"os"
+	"strings"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
 )
 
-func GetGitHubOAuthConfig() *oauth2.Config {
+// Endpoints are the OAuth and REST API URLs of a GitHub deployment
+type Endpoints struct {
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+}
+
+// To resolve the URLs of a GitHub deployment. baseURL (GITHUB_BASE_URL) points
+// at a GitHub Enterprise Server host (e.g. https://github.acme.com) and derives
+// all three URLs; authURL, tokenURL and apiURL (GITHUB_AUTH_URL,
+// GITHUB_TOKEN_URL, GITHUB_API_URL) override them individually. Empty means
+// github.com.
+func ResolveEndpoints(baseURL, authURL, tokenURL, apiURL string) Endpoints {
+	endpoints := Endpoints{
+		AuthURL:  github.Endpoint.AuthURL,
+		TokenURL: github.Endpoint.TokenURL,
+		APIURL:   DefaultAPIBaseURL,
+	}
+
+	if base := strings.TrimRight(baseURL, "/"); base != "" && base != "https://github.com" {
+		endpoints = EnterpriseEndpoints(base)
+	}
 
+	if authURL != "" {
+		endpoints.AuthURL = authURL
+	}
+	if tokenURL != "" {
+		endpoints.TokenURL = tokenURL
+	}
+	if apiURL != "" {
+		endpoints.APIURL = strings.TrimRight(apiURL, "/")
+	}
+
+	return endpoints
+}
+
+// To derive the endpoints of a GitHub Enterprise Server instance from its base URL
+func EnterpriseEndpoints(baseURL string) Endpoints {
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	return Endpoints{
+		AuthURL:  baseURL + "/login/oauth/authorize",
+		TokenURL: baseURL + "/login/oauth/access_token",
+		APIURL:   baseURL + "/api/v3",
+	}
+}
+
+// OAuthApp is the GitHub OAuth app users sign in through
+type OAuthApp struct {
+	ClientID     string
+	ClientSecret string
+	CallbackURL  string
+	Endpoints    Endpoints
+}
+
+func (a OAuthApp) config() *oauth2.Config {
 	return &oauth2.Config{
-		ClientID:     os.Getenv("GITHUB_CLIENT_ID"),
-		ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
-		Endpoint:     github.Endpoint,
-		RedirectURL:  os.Getenv("GITHUB_CALLBACK_URL"),
-		Scopes:       []string{"read:user", "repo"},
+		ClientID:     a.ClientID,
+		ClientSecret: a.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  a.Endpoints.AuthURL,
+			TokenURL: a.Endpoints.TokenURL,
+		},
+		RedirectURL: a.CallbackURL,
+		Scopes:      []string{"read:user", "repo"},
 	}
 }
 
-// To generate GitHub OAuth URL
-func GetAuthURL(state string) string {
-	return GetGitHubOAuthConfig().AuthCodeURL(state)
+// To generate GitHub OAuth URL with a PKCE (S256) challenge for the verifier
+func (a OAuthApp) AuthURL(state, verifier string) string {
+	return a.config().AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// To exchange code for access token, proving possession of the PKCE verifier
+func (a OAuthApp) Exchange(ctx context.Context, code, verifier string) (*oauth2.Token, error) {
+	return a.config().Exchange(ctx, code, oauth2.VerifierOption(verifier))
 }
 
-// To exchange code for access token
-func ExchangeCodeForToken(code string) (*oauth2.Token, error) {
-	ctx := context.Background()
-	return GetGitHubOAuthConfig().Exchange(ctx, code)
+// To create a new random PKCE code verifier
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
 }
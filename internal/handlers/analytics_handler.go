@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/analytics"
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/authz"
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 365
+)
+
+// To report who opened a link, when, and what they looked at
+func (a *App) LinkAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	days := defaultAnalyticsDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		var err error
+		days, err = strconv.Atoi(raw)
+		if err != nil || days <= 0 || days > maxAnalyticsDays {
+			http.Error(w, "❌ days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+	}
+
+	link, ok := a.ownedLink(w, r, authz.Read, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	to := a.now()
+	from := to.AddDate(0, 0, -(days - 1)).Truncate(24 * time.Hour)
+
+	var events []models.ViewEvent
+	if err := a.DB.Where("viewer_link_id = ? AND created_at >= ?", link.ID, from).Find(&events).Error; err != nil {
+		http.Error(w, "❌ Failed to fetch view events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.LinkAnalytics{
+		LinkID:    link.PublicID,
+		RepoName:  link.RepoName,
+		ViewCount: link.ViewCount,
+		Analytics: analytics.Build(events, from, to),
+	})
+}
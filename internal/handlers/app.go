@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/mailer"
+	"github.com/greatdaveo/privycode-server/internal/notify"
+	"github.com/greatdaveo/privycode-server/internal/privyignore"
+	"github.com/greatdaveo/privycode-server/internal/redact"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+// App is everything the handlers depend on. Every handler is a method on it,
+// so two apps (say, two test servers) never share state.
+type App struct {
+	DB     *gorm.DB
+	Config *config.Config
+
+	// Links and Users are how handlers find viewer links and their owners
+	Links store.LinkStore
+	Users store.UserStore
+
+	// GitHub is used by every handler that reads from GitHub for this
+	// deployment. Tests can swap in github.NewFakeClient().
+	GitHub github.Client
+
+	// Mailer sends viewer sign-in links
+	Mailer mailer.Mailer
+
+	// Notifier emails owners about their links; nil disables owner emails
+	Notifier *notify.Notifier
+
+	// Webhooks sends test deliveries right away; nil leaves them queued
+	Webhooks *webhooks.Dispatcher
+
+	// Redactor masks likely secrets in every file served to viewers.
+	// Swap it (or its Rules) to change what gets redacted.
+	Redactor *redact.Redactor
+
+	Clock  func() time.Time
+	Logger *log.Logger
+
+	privyignore *privyignore.Cache
+
+	// To reuse one client per API host for users whose token belongs to another host
+	accountClients sync.Map
+}
+
+// To build an app on db with cfg. Everything else gets a working default
+// (GitHub for cfg's deployment, a log mailer, the wall clock, the standard
+// logger, no owner emails) that callers can replace before serving.
+func NewApp(db *gorm.DB, cfg *config.Config) *App {
+	app := &App{
+		DB:       db,
+		Config:   cfg,
+		Links:    store.NewGormLinkStore(db),
+		Users:    store.NewGormUserStore(db),
+		GitHub:   github.NewClient(cfg.GitHub.Endpoints.APIURL),
+		Redactor: redact.Default(),
+		Clock:    time.Now,
+		Logger:   log.Default(),
+	}
+	app.Mailer = mailer.NewLogMailer(app.Logger)
+
+	// To keep the cache on the app's clock, even if Clock is replaced later
+	app.privyignore = privyignore.NewCache(5*time.Minute, app.now)
+
+	return app
+}
+
+func (a *App) now() time.Time {
+	return a.Clock()
+}
@@ -2,52 +2,63 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"net/url"
 
-	"github.com/google/uuid"
-	"github.com/greatdaveo/privycode-server/config"
-	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/middleware"
 	"github.com/greatdaveo/privycode-server/internal/models"
-	"gorm.io/gorm"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/internal/utils"
 )
 
-func GitHubLoginHandler(w http.ResponseWriter, r *http.Request) {
-	state := uuid.New().String()
-	url := github.GetAuthURL(state)
-	http.Redirect(w, r, url, http.StatusFound)
+func (a *App) GitHubLoginHandler(w http.ResponseWriter, r *http.Request) {
+	// To only allow deep links back into our own frontend
+	returnTo := r.URL.Query().Get("return_to")
+	if _, ok := a.resolveReturnTo(returnTo); !ok {
+		http.Error(w, "❌ return_to is not an allowed URL", http.StatusBadRequest)
+		return
+	}
+
+	state, verifier, err := a.beginOAuthState(w, returnTo)
+	if err != nil {
+		http.Error(w, "❌ Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	authURL := a.Config.GitHub.AuthURL(state, verifier)
+	http.Redirect(w, r, authURL, http.StatusFound)
 }
 
-func GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	// To reject forged or replayed callbacks before touching the code
+	pending, err := a.consumeOAuthState(w, r)
+	if err != nil {
+		http.Error(w, "❌ Invalid login attempt: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	code := r.URL.Query().Get("code")
 	if code == "" {
 		http.Error(w, "Missing code in URL", http.StatusBadRequest)
 		return
 	}
 
-	token, err := github.ExchangeCodeForToken(code)
+	token, err := a.Config.GitHub.Exchange(r.Context(), code, pending.CodeVerifier)
 	if err != nil {
 		http.Error(w, "❌ Failed to exchange code: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// To get the user info with the token
-	client := github.GetGitHubOAuthConfig().Client(r.Context(), token)
-	response, err := client.Get("https://api.github.com/user")
+	githubUser, err := a.GitHub.GetUser(r.Context(), token.AccessToken)
 	if err != nil {
 		http.Error(w, "❌ Failed to fetch user: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	defer response.Body.Close()
-
-	var githubUser struct {
-		Login string `json:"login"`
-		Email string `json:"email"`
-	}
-
-	json.NewDecoder(response.Body).Decode(&githubUser)
-
 	if githubUser.Login == "" {
 		http.Error(w, "❌ Invalid GitHub response", http.StatusInternalServerError)
 		return
@@ -59,21 +70,19 @@ func GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		email = fmt.Sprintf("%s@users.noreply.github.com", githubUser.Login)
 	}
 
-	dbInstance := config.DB
-
 	// To check if user exists
-	var existingUser models.User
-	err = dbInstance.Where("git_hub_username = ?", githubUser.Login).First(&existingUser).Error
+	existingUser, err := a.Users.ByGitHubUsername(githubUser.Login)
 
-	if err == gorm.ErrRecordNotFound {
+	if err == store.ErrNotFound {
 		// To create new user
-		newUser := models.User{
+		existingUser = &models.User{
 			Email:          email,
 			GitHubUsername: githubUser.Login,
+			GitHubAPIURL:   a.Config.GitHub.Endpoints.APIURL,
 			GitHubToken:    token.AccessToken,
 		}
 
-		if err := dbInstance.Create(&newUser).Error; err != nil {
+		if err := a.Users.Create(existingUser); err != nil {
 			http.Error(w, "❌ Failed to create user", http.StatusInternalServerError)
 			return
 		}
@@ -82,33 +91,130 @@ func GitHubCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	} else if err == nil {
 		// Optionally update GitHub Token if needed
 		existingUser.GitHubToken = token.AccessToken
-		dbInstance.Save(&existingUser)
+		existingUser.GitHubAPIURL = a.Config.GitHub.Endpoints.APIURL
+		a.Users.Save(existingUser)
 	} else {
 		http.Error(w, "❌ Database error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// // To set cookie with the token
-	// http.SetCookie(w, &http.Cookie{
-	// 	Name:     "github_token",
-	// 	Value:    token.AccessToken,
-	// 	Path:     "/",
-	// 	HttpOnly: true,
-	// 	Secure:   true,
-	// SameSite: http.SameSiteNoneMode,
-	// 	Domain:   ".privycode.com",
-	// 	// Expires:  time.Now().Add(72 * time.Hour),
-	// })
+	// To hand the frontend a single-use login code rather than the session
+	// token, which would otherwise sit in browser history and Referer headers
+	loginCode, err := a.issueLoginCode(existingUser)
+	if err != nil {
+		http.Error(w, "❌ Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+
+	// To send the user back to where the login started (checked again, in case the allowlist changed)
+	target, ok := a.resolveReturnTo(pending.ReturnTo)
+	if !ok {
+		target, _ = a.resolveReturnTo("")
+	}
+
+	redirectURL, err := url.Parse(target)
+	if err != nil {
+		http.Error(w, "❌ Invalid redirect URL", http.StatusInternalServerError)
+		return
+	}
+	query := redirectURL.Query()
+	query.Set("login_code", loginCode)
+	redirectURL.RawQuery = query.Encode()
+
+	fmt.Printf("🔄 Redirecting %s to %s\n", existingUser.GitHubUsername, target)
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+// To exchange the login code from the callback redirect for a PrivyCode
+// session; the GitHub token never leaves the server
+func (a *App) ExchangeLoginCodeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Code == "" {
+		http.Error(w, "❌ Missing login code", http.StatusBadRequest)
+		return
+	}
 
-	// To redirect to the frontend dashboard without exposing token
-	frontendURL := os.Getenv("FRONTEND_URL")
+	user, err := a.consumeLoginCode(payload.Code)
+	if errors.Is(err, errLoginCodeInvalid) {
+		http.Error(w, "❌ Invalid login code, please log in again", http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, "❌ Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sessionToken, session, err := a.createSession(user, r)
+	if err != nil {
+		http.Error(w, "❌ Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Session{
+		Token:     sessionToken,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+// To end the session used to make this request
+func (a *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	session := middleware.GetSessionFromContext(r)
+	if session == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := a.DB.Unscoped().Delete(&models.Session{}, session.ID).Error; err != nil {
+		http.Error(w, "❌ Could not log out", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Message{Message: "Logged out"})
+}
+
+// To end every session of the logged-in user, on all devices
+func (a *App) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result := a.DB.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Session{})
+	if result.Error != nil {
+		http.Error(w, "❌ Could not log out sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.SessionsRevoked{
+		Message:         "Logged out of all sessions",
+		SessionsRevoked: result.RowsAffected,
+	})
+}
+
+// To create a session row for the user and return it with the raw session token
+func (a *App) createSession(user *models.User, r *http.Request) (string, *models.Session, error) {
+	rawToken, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := a.now()
+	session := models.Session{
+		UserID:     user.ID,
+		TokenHash:  utils.HashToken(rawToken),
+		ExpiresAt:  now.Add(a.Config.SessionTTL),
+		LastUsedAt: now,
+		UserAgent:  r.UserAgent(),
+	}
 
-	if frontendURL == "" {
-		frontendURL = "http://localhost:5173"
+	if err := a.DB.Create(&session).Error; err != nil {
+		return "", nil, err
 	}
 
-	redirectURL := fmt.Sprintf("%s/dashboard?token=%s", frontendURL, token.AccessToken)
-	
-	fmt.Printf("🔄 Redirecting user to: %s\n", redirectURL)
-	http.Redirect(w, r, redirectURL, http.StatusFound)
+	return rawToken, &session, nil
 }
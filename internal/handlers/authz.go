@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/greatdaveo/privycode-server/internal/authz"
+	"github.com/greatdaveo/privycode-server/internal/middleware"
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// To load the viewer link a management request is about (by public ID), if the logged-in
+// user may perform the action on it. Writes the error response and returns
+// false otherwise; links of other users are reported as not found.
+func (a *App) ownedLink(w http.ResponseWriter, r *http.Request, action authz.Action, publicID string) (*models.ViewerLink, bool) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	link, err := authz.Link(a.Links, user, action, publicID)
+	if errors.Is(err, authz.ErrNotFound) {
+		http.Error(w, "❌ Link not found", http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, "❌ Database error: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return link, true
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// To pick the client for the GitHub host a user's token was issued by, so a
+// token is never sent to a different host than the one that issued it
+func (a *App) githubClientFor(user *models.User) github.Client {
+	if user.GitHubAPIURL == "" || user.GitHubAPIURL == a.Config.GitHub.Endpoints.APIURL {
+		return a.GitHub
+	}
+
+	client, _ := a.accountClients.LoadOrStore(user.GitHubAPIURL, github.NewClient(user.GitHubAPIURL))
+	return client.(github.Client)
+}
+
+// To forward a GitHub error status and body, or report a transport failure
+func writeGitHubError(w http.ResponseWriter, prefix string, err error) {
+	var apiErr *github.APIError
+	if errors.As(err, &apiErr) {
+		http.Error(w, prefix+apiErr.Body, apiErr.StatusCode)
+		return
+	}
+
+	http.Error(w, prefix+err.Error(), http.StatusBadGateway)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/middleware"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/testutil"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+)
+
+// testServer is an App on an in-memory SQLite database with a fake GitHub
+type testServer struct {
+	t      *testing.T
+	app    *App
+	github *github.FakeClient
+	mux    *http.ServeMux
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db := testutil.DB(t)
+	app := NewApp(db, config.Defaults())
+	fake := github.NewFakeClient()
+	app.GitHub = fake
+
+	auth := middleware.AuthMiddleware(db, app.Clock)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/update-link/", auth(app.UpdateViewerLinkHandler))
+	mux.HandleFunc("/delete-link/", auth(app.DeleteViewerLinkHandler))
+	mux.HandleFunc("GET /links/{id}/analytics", auth(app.LinkAnalyticsHandler))
+	mux.HandleFunc("/view-files/", app.ViewFileHandler)
+	mux.HandleFunc("/view-folder/", app.ViewerFolderHandler)
+
+	return &testServer{t: t, app: app, github: fake, mux: mux}
+}
+
+// To create a user with a live session, returning its Authorization header
+func (s *testServer) login(username string) (*models.User, string) {
+	s.t.Helper()
+
+	user := testutil.User(s.t, s.app.DB, username)
+	return user, testutil.Session(s.t, s.app.DB, user, time.Now().Add(time.Hour))
+}
+
+// To create a viewer link owned by user
+func (s *testServer) createLink(user *models.User) *models.ViewerLink {
+	return testutil.Link(s.t, s.app.DB, user, "portfolio", 10)
+}
+
+func (s *testServer) do(method, path, body, authorization string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOtherUsersLinksAreNotFound(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceAuth := s.login("alice")
+	_, mallory := s.login("mallory")
+	link := s.createLink(alice)
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodPut, "/update-link/" + link.PublicID, `{"expires_in_days": 30, "max_views": 1000, "disable_redaction": true}`},
+		{http.MethodDelete, "/delete-link/" + link.PublicID, ""},
+		{http.MethodGet, "/links/" + link.PublicID + "/analytics", ""},
+	}
+
+	for _, req := range requests {
+		rec := s.do(req.method, req.path, req.body, mallory)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s as another user = %d, want 404 (%s)", req.method, req.path, rec.Code, rec.Body)
+		}
+	}
+
+	// To make sure the 404s come from the ownership check, not a missing route
+	if rec := s.do(http.MethodGet, "/links/"+link.PublicID+"/analytics", "", aliceAuth); rec.Code != http.StatusOK {
+		t.Fatalf("owner's analytics = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+
+	after, err := s.app.Links.ByToken(link.Token)
+	if err != nil {
+		t.Fatalf("link is gone after another user's requests: %v", err)
+	}
+	if !after.ExpiresAt.Equal(link.ExpiresAt) || after.MaxViews != link.MaxViews || after.DisableRedaction != link.DisableRedaction {
+		t.Errorf("link changed after another user's requests: got expires %v, max views %d, disable redaction %v",
+			after.ExpiresAt, after.MaxViews, after.DisableRedaction)
+	}
+}
+
+func TestViewFileRefusesSymlinks(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.login("alice")
+	repo := s.github.AddRepo("alice", "portfolio", map[string]string{
+		"public/README.md": "hello",
+		"secret/.env":      "API_KEY=hunter2",
+	})
+	repo.Symlinks = map[string]string{"public/env": "secret/.env"}
+
+	link := s.createLink(alice)
+	link.AllowPaths = []string{"public/**"}
+	if err := s.app.DB.Save(link).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := s.do(http.MethodGet, "/view-files/"+link.Token+"?path=public/README.md", "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("regular file = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+
+	rec := s.do(http.MethodGet, "/view-files/"+link.Token+"?path=public/env", "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("symlink = %d, want 404", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("symlink served its target outside the scope: %s", rec.Body)
+	}
+}
+
+func TestLoginCodeIsExchangedOnce(t *testing.T) {
+	s := newTestServer(t)
+	s.mux.HandleFunc("POST /auth/exchange", s.app.ExchangeLoginCodeHandler)
+	alice, _ := s.login("alice")
+
+	code, err := s.app.issueLoginCode(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"code": "` + code + `"}`
+
+	rec := s.do(http.MethodPost, "/auth/exchange", body, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first exchange = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+
+	var session api.Session
+	if err := json.NewDecoder(rec.Body).Decode(&session); err != nil || session.Token == "" {
+		t.Fatalf("first exchange returned no token: %v", err)
+	}
+	var stored models.Session
+	if err := s.app.DB.Where("token_hash = ?", utils.HashToken(session.Token)).First(&stored).Error; err != nil || stored.UserID != alice.ID {
+		t.Errorf("exchanged token has no session for alice: %v", err)
+	}
+
+	if rec := s.do(http.MethodPost, "/auth/exchange", body, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("second exchange = %d, want 401", rec.Code)
+	}
+
+	// To refuse a code that was not exchanged in time
+	late, err := s.app.issueLoginCode(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.app.Clock = func() time.Time { return time.Now().Add(2 * loginCodeTTL) }
+	if rec := s.do(http.MethodPost, "/auth/exchange", `{"code": "`+late+`"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired code = %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Long enough for the frontend to load and post the code back, no longer
+const loginCodeTTL = time.Minute
+
+var errLoginCodeInvalid = errors.New("login code is unknown, expired or already used")
+
+// To issue a login code for a user who just signed in with GitHub, returning the raw code
+func (a *App) issueLoginCode(user *models.User) (string, error) {
+	code, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	// To clean up codes that were never exchanged
+	a.DB.Unscoped().Where("expires_at < ?", a.now()).Delete(&models.LoginCode{})
+
+	loginCode := models.LoginCode{
+		UserID:    user.ID,
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: a.now().Add(loginCodeTTL),
+	}
+	if err := a.DB.Create(&loginCode).Error; err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// To consume a login code, returning the user it was issued to
+func (a *App) consumeLoginCode(code string) (*models.User, error) {
+	var loginCode models.LoginCode
+	err := a.DB.Preload("User").Where("code_hash = ?", utils.HashToken(code)).First(&loginCode).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errLoginCodeInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	// To make the code single use; a concurrent replay loses the delete race
+	result := a.DB.Unscoped().Delete(&models.LoginCode{}, loginCode.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || a.now().After(loginCode.ExpiresAt) {
+		return nil, errLoginCodeInvalid
+	}
+
+	return &loginCode.User, nil
+}
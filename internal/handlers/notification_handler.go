@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/middleware"
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// To show which emails the user gets about their links
+func (a *App) GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.NewNotificationPreferences(user))
+}
+
+// To change which emails the user gets; omitted fields stay as they are
+func (a *App) UpdateNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload api.NotificationPreferences
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "❌ Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	updates := map[string]any{}
+	if payload.FirstOpened != nil {
+		updates["notify_first_opened"] = *payload.FirstOpened
+		user.NotifyFirstOpened = *payload.FirstOpened
+	}
+	if payload.ExpiringSoon != nil {
+		updates["notify_expiring"] = *payload.ExpiringSoon
+		user.NotifyExpiring = *payload.ExpiringSoon
+	}
+	if payload.WeeklyDigest != nil {
+		updates["weekly_digest"] = *payload.WeeklyDigest
+		user.WeeklyDigest = *payload.WeeklyDigest
+	}
+
+	if len(updates) > 0 {
+		if err := a.DB.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			http.Error(w, "❌ Could not save notification preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.NewNotificationPreferences(user))
+}
+
+// To email the owner about the first view without making the viewer wait for the mail server
+func (a *App) notifyOwnerFirstOpened(link *models.ViewerLink) {
+	if a.Notifier == nil {
+		return
+	}
+
+	linkCopy := *link
+	go func() {
+		if err := a.Notifier.LinkFirstOpened(context.Background(), &linkCopy); err != nil {
+			a.Logger.Printf("❌ Could not email owner of link %d: %v", linkCopy.ID, err)
+		}
+	}()
+}
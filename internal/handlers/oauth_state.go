@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthStateCookie = "privycode_oauth_state"
+	oauthStateTTL    = 10 * time.Minute
+)
+
+var (
+	errStateMissing  = errors.New("missing OAuth state")
+	errStateMismatch = errors.New("OAuth state does not match this browser")
+	errStateUnknown  = errors.New("OAuth state is unknown or was already used")
+	errStateExpired  = errors.New("OAuth state has expired, please log in again")
+)
+
+// To persist a new login attempt and bind it to the browser with a cookie
+func (a *App) beginOAuthState(w http.ResponseWriter, returnTo string) (state, verifier string, err error) {
+	state, err = utils.GenerateSecureToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier = github.GenerateVerifier()
+
+	// To clean up abandoned login attempts
+	a.DB.Unscoped().Where("expires_at < ?", a.now()).Delete(&models.OAuthState{})
+
+	pending := models.OAuthState{
+		StateHash:    utils.HashToken(state),
+		CodeVerifier: verifier,
+		ReturnTo:     returnTo,
+		ExpiresAt:    a.now().Add(oauthStateTTL),
+	}
+	if err := a.DB.Create(&pending).Error; err != nil {
+		return "", "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/github/callback",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   a.Config.Production(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return state, verifier, nil
+}
+
+// To verify the callback state against the browser cookie and consume it, so it cannot be replayed
+func (a *App) consumeOAuthState(w http.ResponseWriter, r *http.Request) (*models.OAuthState, error) {
+	state := r.URL.Query().Get("state")
+	if state == "" {
+		return nil, errStateMissing
+	}
+
+	// To clear the state cookie whatever the outcome
+	http.SetCookie(w, &http.Cookie{
+		Name:   oauthStateCookie,
+		Value:  "",
+		Path:   "/github/callback",
+		MaxAge: -1,
+	})
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || cookie.Value != state {
+		return nil, errStateMismatch
+	}
+
+	var pending models.OAuthState
+	err = a.DB.Where("state_hash = ?", utils.HashToken(state)).First(&pending).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errStateUnknown
+	} else if err != nil {
+		return nil, err
+	}
+
+	// To make the state single use; a concurrent replay loses the delete race
+	result := a.DB.Unscoped().Delete(&models.OAuthState{}, pending.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errStateUnknown
+	}
+
+	if a.now().After(pending.ExpiresAt) {
+		return nil, errStateExpired
+	}
+
+	return &pending, nil
+}
+
+// To resolve a return_to value into an absolute frontend URL.
+// Relative paths are resolved against FRONTEND_URL; absolute URLs must use an
+// allowed origin (FRONTEND_URL or one listed in OAUTH_RETURN_TO_ALLOWLIST).
+func (a *App) resolveReturnTo(returnTo string) (string, bool) {
+	frontendURL := a.Config.FrontendURL
+
+	if returnTo == "" {
+		return frontendURL + "/dashboard", true
+	}
+
+	// To reject protocol-relative and backslash tricks such as //evil.com or /\evil.com
+	if strings.HasPrefix(returnTo, "/") {
+		if strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+			return "", false
+		}
+		return frontendURL + returnTo, true
+	}
+
+	target, err := url.Parse(returnTo)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return "", false
+	}
+
+	origin := target.Scheme + "://" + target.Host
+	for _, allowed := range a.Config.AllowedOrigins() {
+		if origin == allowed {
+			return target.String(), true
+		}
+	}
+
+	return "", false
+}
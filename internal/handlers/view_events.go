@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+// To record a successful request to a viewer endpoint
+func (a *App) recordViewEvent(r *http.Request, link *models.ViewerLink, session *models.ViewerSession, endpoint, path string) {
+	event := models.ViewEvent{
+		ViewerLinkID: link.ID,
+		Endpoint:     endpoint,
+		Path:         path,
+		IPHash:       a.hashIP(a.clientIP(r)),
+		UserAgent:    r.UserAgent(),
+		Referrer:     a.referrerHost(r),
+	}
+
+	if session != nil {
+		event.ViewerSessionID = &session.ID
+		event.ViewerEmail = session.Email
+	}
+
+	if err := a.DB.Create(&event).Error; err != nil {
+		a.Logger.Printf("❌ Failed to record view event for link %d: %v", link.ID, err)
+	}
+}
+
+// ViewerReferrerHeader lets the frontend pass on document.referrer, since
+// the API only ever sees the frontend itself as the Referer
+const ViewerReferrerHeader = "X-Viewer-Referrer"
+
+// To get the host the viewer came from, ignoring our own frontend
+func (a *App) referrerHost(r *http.Request) string {
+	raw := r.Header.Get(ViewerReferrerHeader)
+	if raw == "" {
+		raw = r.Referer()
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+
+	if frontend, err := url.Parse(a.Config.FrontendURL); err == nil && frontend.Host == parsed.Host {
+		return ""
+	}
+
+	return strings.ToLower(parsed.Hostname())
+}
+
+// To get the client IP. X-Forwarded-For is only believed when the request
+// comes from one of the TRUSTED_PROXIES; the client is then the nearest hop
+// that is not itself a trusted proxy, since anything further left can be
+// forged by the client.
+func (a *App) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !a.trustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !a.trustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+
+	return host
+}
+
+func (a *App) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range a.Config.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// To pseudonymise an IP with a keyed hash (IP_HASH_SALT), so raw IPs are never stored
+func (a *App) hashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.Config.IPHashSalt))
+	mac.Write([]byte(ip))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/greatdaveo/privycode-server/config"
+)
+
+func TestClientIP(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	app := &App{Config: cfg}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"forged header from an untrusted client", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:443", "198.51.100.1", "198.51.100.1"},
+		{"client-supplied hops left of the proxy's", "10.0.0.2:443", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:443", "198.51.100.1, 10.0.0.5", "198.51.100.1"},
+		{"trusted proxy without the header", "10.0.0.2:443", "", "10.0.0.2"},
+		{"IPv4-mapped trusted proxy", "[::ffff:10.0.0.2]:443", "198.51.100.1", "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/view/token", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			if got := app.clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
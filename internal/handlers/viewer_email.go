@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/mailer"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+)
+
+const magicLinkTTL = 15 * time.Minute
+
+// To email a one-time sign-in link to a viewer of an email-verified link
+func (a *App) ViewerRequestAccessHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/view-request-access/")
+	if token == "" {
+		http.Error(w, "❌ Missing token", http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "❌ Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	email, err := normalizeEmail(payload.Email)
+	if err != nil {
+		http.Error(w, "❌ Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	link, err := a.Links.ByToken(token)
+	if err != nil {
+		http.Error(w, "❌ Invalid or expired link", http.StatusNotFound)
+		return
+	}
+
+	if a.now().After(link.ExpiresAt) {
+		a.notifyLinkExpired(link)
+		http.Error(w, "❌ Link has expired", http.StatusForbidden)
+		return
+	}
+
+	if !link.RequiresEmail() {
+		http.Error(w, "❌ This link does not use email verification", http.StatusBadRequest)
+		return
+	}
+
+	// To answer the same way whether or not the email is allowed, so the allowlist cannot be probed
+	response := api.Message{
+		Message: "If this email is allowed to view the link, a sign-in link is on its way",
+	}
+
+	if !link.EmailAllowed(email) {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	code, err := utils.GenerateSecureToken()
+	if err != nil {
+		http.Error(w, "❌ Could not create sign-in link", http.StatusInternalServerError)
+		return
+	}
+
+	magicLink := models.ViewerMagicLink{
+		ViewerLinkID: link.ID,
+		Email:        email,
+		TokenHash:    utils.HashToken(code),
+		ExpiresAt:    a.now().Add(magicLinkTTL),
+	}
+	if err := a.DB.Create(&magicLink).Error; err != nil {
+		http.Error(w, "❌ Could not create sign-in link", http.StatusInternalServerError)
+		return
+	}
+
+	// To point at the frontend, which posts the code back; a GET would let mail scanners burn it
+	signInURL := fmt.Sprintf("%s/view/%s?magic=%s", a.Config.FrontendURL, url.PathEscape(link.Token), url.QueryEscape(code))
+
+	err = a.Mailer.Send(r.Context(), mailer.Message{
+		To:      email,
+		Subject: "Your PrivyCode sign-in link",
+		Body: fmt.Sprintf("Someone (hopefully you) asked to view %s on PrivyCode.\n\n"+
+			"Open this link within %d minutes to continue:\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			link.RepoName, int(magicLinkTTL.Minutes()), signInURL),
+	})
+	if err != nil {
+		a.Logger.Printf("❌ Failed to send magic link: %v", err)
+		http.Error(w, "❌ Could not send sign-in email", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// To exchange a magic link code for a viewer session tied to the verified email
+func (a *App) ViewerVerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/view-verify/")
+	if token == "" {
+		http.Error(w, "❌ Missing token", http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Code == "" {
+		http.Error(w, "❌ Missing code", http.StatusBadRequest)
+		return
+	}
+
+	link, err := a.Links.ByToken(token)
+	if err != nil {
+		http.Error(w, "❌ Invalid or expired link", http.StatusNotFound)
+		return
+	}
+
+	if a.now().After(link.ExpiresAt) {
+		a.notifyLinkExpired(link)
+		http.Error(w, "❌ Link has expired", http.StatusForbidden)
+		return
+	}
+
+	var magicLink models.ViewerMagicLink
+	err = a.DB.
+		Where("token_hash = ? AND viewer_link_id = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(payload.Code), link.ID, a.now()).
+		First(&magicLink).Error
+	if err != nil {
+		http.Error(w, "❌ Sign-in link is invalid, expired or already used", http.StatusUnauthorized)
+		return
+	}
+
+	// To use the code exactly once, even if it is submitted twice at the same time
+	result := a.DB.Model(&models.ViewerMagicLink{}).
+		Where("id = ? AND used_at IS NULL", magicLink.ID).
+		UpdateColumn("used_at", a.now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		http.Error(w, "❌ Sign-in link is invalid, expired or already used", http.StatusUnauthorized)
+		return
+	}
+
+	// To re-check the allowlist, in case the owner changed it since the email was sent
+	if !link.RequiresEmail() || !link.EmailAllowed(magicLink.Email) {
+		http.Error(w, "❌ This email can no longer view the link", http.StatusForbidden)
+		return
+	}
+
+	sessionToken, session, err := a.startViewerSession(link, magicLink.Email)
+	if errors.Is(err, store.ErrViewLimitReached) {
+		http.Error(w, "❌ View limit reached", http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, "❌ Could not start viewer session", http.StatusInternalServerError)
+		return
+	}
+
+	a.setViewerSessionCookie(w, link, sessionToken, session.ExpiresAt)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.ViewerSession{
+		ViewerSession: sessionToken,
+		ExpiresAt:     session.ExpiresAt,
+		Email:         session.Email,
+	})
+}
+
+// To validate and lower-case an email address
+func normalizeEmail(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	address, err := mail.ParseAddress(raw)
+	if err != nil || address.Address != raw {
+		return "", errors.New("invalid email address")
+	}
+
+	return strings.ToLower(address.Address), nil
+}
+
+// To validate an access mode and its email allowlist, normalising the entries
+func validateEmailAccess(mode string, allowed []string) ([]string, error) {
+	if mode != models.AccessPublic && mode != models.AccessEmail {
+		return nil, fmt.Errorf("access_mode must be %q or %q", models.AccessPublic, models.AccessEmail)
+	}
+
+	normalized := make([]string, 0, len(allowed))
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+
+		if strings.HasPrefix(entry, "@") && len(entry) > 1 && !strings.Contains(entry[1:], "@") {
+			normalized = append(normalized, entry)
+			continue
+		}
+
+		email, err := normalizeEmail(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed email %q", entry)
+		}
+		normalized = append(normalized, email)
+	}
+
+	return normalized, nil
+}
@@ -2,26 +2,42 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/authz"
 	"github.com/greatdaveo/privycode-server/internal/middleware"
 	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/pathrules"
 	"github.com/greatdaveo/privycode-server/internal/utils"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
 )
 
 type ViewerLinkRequest struct {
-	RepoName  string `json:"repo_name"`
-	ExpiresIn int    `json:"expires_in_days"`
-	MaxViews  int    `json:"max_views"`
+	RepoName     string `json:"repo_name"`
+	ExpiresIn    int    `json:"expires_in_days"`
+	MaxViews     int    `json:"max_views"`
+	Ref          string `json:"ref"`           // branch, tag or commit SHA (defaults to the default branch)
+	FollowBranch bool   `json:"follow_branch"` // to show the latest commit of Ref instead of freezing it
+
+	AllowPaths []string `json:"allow_paths"` // globs the link is limited to, e.g. "services/payments/**"
+	DenyPaths  []string `json:"deny_paths"`  // globs hidden from viewers, e.g. "**/.env"
+
+	DisableRedaction bool `json:"disable_redaction"` // to serve files without masking secrets
+
+	Password string `json:"password"` // optional; viewers must unlock the link with it
+
+	AccessMode    string   `json:"access_mode"`    // "public" (default) or "email"
+	AllowedEmails []string `json:"allowed_emails"` // for "email": addresses or "@domain.com"
 }
 
-func GenerateViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
+// RedactionsHeader tells the frontend how many values were masked in a file
+const RedactionsHeader = "X-PrivyCode-Redactions"
+
+func (a *App) GenerateViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r)
 	// fmt.Println("User: ", user)
 
@@ -37,50 +53,98 @@ func GenerateViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validatePathRules(req.AllowPaths, req.DenyPaths); err != nil {
+		http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.AccessMode == "" {
+		req.AccessMode = models.AccessPublic
+	}
+
+	allowedEmails, err := validateEmailAccess(req.AccessMode, req.AllowedEmails)
+	if err != nil {
+		http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.AccessMode == models.AccessEmail && req.Password != "" {
+		http.Error(w, "❌ A link can use a password or email verification, not both", http.StatusBadRequest)
+		return
+	}
+
+	passwordHash := ""
+	if req.Password != "" {
+		passwordHash, err = hashLinkPassword(req.Password)
+		if err != nil {
+			http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	token := utils.GenerateToken()
 	// To calculate expiring date
 	days := req.ExpiresIn
 	if days <= 0 {
 		days = 3 // to set default expiration to 3 days
 	}
-	expiration := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	expiration := a.now().Add(time.Duration(days) * 24 * time.Hour)
 
-	link := models.ViewerLink{
-		RepoName:  req.RepoName,
-		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: expiration,
-		MaxViews:  req.MaxViews,
-		ViewCount: 0,
-	}
+	client := a.githubClientFor(user)
 
 	// To ensure the repository exist before saving
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s", user.GitHubUsername, req.RepoName)
-	reqGitHub, _ := http.NewRequest("GET", apiURL, nil)
-	reqGitHub.Header.Set("Authorization", "token "+user.GitHubToken)
-	reqGitHub.Header.Set("Accept", "application/vnd.github.v3+json")
+	repo, err := client.GetRepo(r.Context(), user.GitHubToken, user.GitHubUsername, req.RepoName)
+	if err != nil {
+		http.Error(w, "❌ Repository not found or inaccessible", http.StatusNotFound)
+		return
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(reqGitHub)
+	// To pin the link to the commit the ref points at right now
+	ref := req.Ref
+	if ref == "" {
+		ref = repo.DefaultBranch
+	}
 
-	if err != nil || resp.StatusCode != 200 {
-		http.Error(w, "❌ Repository not found or inaccessible", http.StatusNotFound)
+	commitSHA, err := client.ResolveRef(r.Context(), user.GitHubToken, user.GitHubUsername, req.RepoName, ref)
+	if err != nil {
+		http.Error(w, "❌ Branch, tag or commit not found: "+ref, http.StatusBadRequest)
 		return
 	}
 
-	if err := config.DB.Create(&link).Error; err != nil {
+	link := models.ViewerLink{
+		RepoName:     req.RepoName,
+		UserID:       user.ID,
+		Token:        token,
+		ExpiresAt:    expiration,
+		MaxViews:     req.MaxViews,
+		ViewCount:    0,
+		Ref:          ref,
+		CommitSHA:    commitSHA,
+		FollowBranch: req.FollowBranch,
+		AllowPaths:   req.AllowPaths,
+		DenyPaths:    req.DenyPaths,
+
+		DisableRedaction: req.DisableRedaction,
+		PasswordHash:     passwordHash,
+		AccessMode:       req.AccessMode,
+		AllowedEmails:    allowedEmails,
+	}
+
+	if err := a.Links.Create(&link); err != nil {
 		http.Error(w, "❌ Could not create viewer link", http.StatusInternalServerError)
+		return
 	}
 
-	viewerURL := fmt.Sprintf("http://localhost:8080/view/%s", token)
+	a.notifyLinkEvent(&link, webhooks.EventLinkCreated)
+
+	response := api.NewLink(&link, a.Config.FrontendURL)
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{
-		"viewer_url": viewerURL,
-	})
+	json.NewEncoder(w).Encode(response)
 }
 
-func ViewerAccessHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) ViewerAccessHandler(w http.ResponseWriter, r *http.Request) {
 	// To extract the token from the URL
 	token := strings.TrimPrefix(r.URL.Path, "/view/")
 	if token == "" {
@@ -88,90 +152,57 @@ func ViewerAccessHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbInstance := config.DB
-	var link models.ViewerLink
-
-	result := dbInstance.Where("token = ?", token).First(&link)
-	if result.Error != nil {
+	link, err := a.Links.ByToken(token)
+	if err != nil {
 		http.Error(w, "❌ Invalid or expired link", http.StatusNotFound)
 		return
 	}
 
 	// To check if the link has expired
-	if time.Now().After(link.ExpiresAt) {
+	if a.now().After(link.ExpiresAt) {
+		a.notifyLinkExpired(link)
 		http.Error(w, "❌ Link has expired", http.StatusForbidden)
 		return
 	}
 
-	// To check the max views (Optional)
-	if link.MaxViews > 0 && link.ViewCount >= link.MaxViews {
-		http.Error(w, "❌ View limit reached", http.StatusForbidden)
+	// To require the password or a verified email, and count the view if this starts a session
+	session, ok := a.requireViewerAccess(w, r, link)
+	if !ok {
 		return
 	}
 
-	// To increase the view count
-	link.ViewCount++
-	dbInstance.Save(&link)
-
 	// To get the owner of the repo
-	var user models.User
-	err := dbInstance.First(&user, link.UserID).Error
+	user, err := a.Users.ByID(link.UserID)
 	if err != nil {
 		http.Error(w, "❌ User not found", http.StatusInternalServerError)
 		return
 	}
 
-	// To build GitHub API request
-	client := &http.Client{}
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents", user.GitHubUsername, link.RepoName)
-
-	// fmt.Println("GitHub API URL:", apiURL)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
+	// To work out which paths this viewer may see
+	scope, err := a.loadViewerScope(r.Context(), user, link)
 	if err != nil {
-		http.Error(w, "❌ Failed to build request", http.StatusInternalServerError)
+		writeGitHubError(w, "❌ Failed to load .privyignore: ", err)
 		return
 	}
 
-	req.Header.Set("Authorization", "token "+user.GitHubToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	// To send the request
-	resp, err := client.Do(req)
+	// To list the repository root from GitHub
+	contents, err := a.githubClientFor(user).ListContents(r.Context(), user.GitHubToken, user.GitHubUsername, link.RepoName, "", link.ContentRef())
 	if err != nil {
-		http.Error(w, "❌ HTTP error: "+err.Error(), http.StatusInternalServerError)
+		writeGitHubError(w, "", err)
 		return
 	}
+	contents = scope.filter(contents)
 
-	defer resp.Body.Close()
-
-	// To read error response body
-	if resp.StatusCode != 200 {
-		errorBody, _ := io.ReadAll(resp.Body)
-		http.Error(w, string(errorBody), resp.StatusCode)
-		return
-	}
-	// To parse GitHub response
-	var contents []struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
-		Path string `json:"path"`
-		URL  string `json:"url"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&contents); err != nil {
-		http.Error(w, "❌ Failed to parse GitHub response", http.StatusInternalServerError)
-		return
-	}
+	a.recordViewEvent(r, link, session, models.EndpointRepo, "")
 
 	// To return the content list as JSON
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(contents)
+	json.NewEncoder(w).Encode(api.NewContentEntries(contents))
 
 	// fmt.Fprintf(w, "✅ Access granted to repo: %s", link.RepoName)
 }
 
-func ViewFileHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) ViewFileHandler(w http.ResponseWriter, r *http.Request) {
 	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/view-files/"), "/")
 
 	if len(segments) < 1 {
@@ -183,18 +214,16 @@ func ViewFileHandler(w http.ResponseWriter, r *http.Request) {
 
 	// fmt.Println("Extracted Token:", token)
 
-	path := r.URL.Query().Get("path")
+	path := pathrules.Clean(r.URL.Query().Get("path"))
 
 	if token == "" || path == "" {
 		http.Error(w, "❌ Missing token or path", http.StatusBadRequest)
 		return
 	}
 
-	dbInstance := config.DB
-
 	// To get the viewer link (including soft-deleted link)
-	var link models.ViewerLink
-	if err := dbInstance.Unscoped().Where("token = ?", token).First(&link).Error; err != nil {
+	link, err := a.Links.ByTokenWithDeleted(token)
+	if err != nil {
 		http.Error(w, "❌ Invalid link or deleted link", http.StatusNotFound)
 		return
 	}
@@ -206,45 +235,67 @@ func ViewFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// To check expiration
-	if time.Now().After(link.ExpiresAt) {
+	if a.now().After(link.ExpiresAt) {
+		a.notifyLinkExpired(link)
 		http.Error(w, "❌ This link has expired", http.StatusForbidden)
 		return
 	}
-	// To check view limits
-	if link.MaxViews > 0 && link.ViewCount >= link.MaxViews {
-		http.Error(w, "❌ View limit reached", http.StatusForbidden)
+
+	session, ok := a.requireViewerAccess(w, r, link)
+	if !ok {
 		return
 	}
 
-	var user models.User
-	if err := dbInstance.First(&user, link.UserID).Error; err != nil {
+	user, err := a.Users.ByID(link.UserID)
+	if err != nil {
 		http.Error(w, "❌ User not found", http.StatusInternalServerError)
 		return
 	}
 
-	// To request file content from GitHub
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", user.GitHubUsername, link.RepoName, path)
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	req.Header.Set("Authorization", "token "+user.GitHubToken)
-	req.Header.Set("Accept", "application/vnd.github.v3.raw")
+	// To refuse files outside the link's scope, without revealing whether they exist
+	scope, err := a.loadViewerScope(r.Context(), user, link)
+	if err != nil {
+		writeGitHubError(w, "❌ Failed to load .privyignore: ", err)
+		return
+	}
 
-	client := &http.Client{}
-	response, err := client.Do(req)
+	if !scope.allowsFile(path) {
+		http.Error(w, "❌ File not found", http.StatusNotFound)
+		return
+	}
 
-	if err != nil || response.StatusCode != 200 {
-		body, _ := io.ReadAll(response.Body)
-		http.Error(w, fmt.Sprintf("❌ GitHub error: %s", body), response.StatusCode)
+	// To refuse symlinks, whose target may lie outside the scope
+	isFile, err := a.regularFile(r.Context(), user, link, path)
+	if err != nil {
+		writeGitHubError(w, "❌ GitHub error: ", err)
+		return
+	}
+	if !isFile {
+		http.Error(w, "❌ File not found", http.StatusNotFound)
 		return
 	}
 
-	defer response.Body.Close()
+	// To request file content from GitHub
+	content, err := a.githubClientFor(user).GetFileRaw(r.Context(), user.GitHubToken, user.GitHubUsername, link.RepoName, path, link.ContentRef())
+	if err != nil {
+		writeGitHubError(w, "❌ GitHub error: ", err)
+		return
+	}
+
+	// To mask secrets unless the owner opted this link out
+	redactions := 0
+	if !link.DisableRedaction {
+		content, redactions = a.Redactor.Redact(path, content)
+	}
 
-	content, _ := io.ReadAll(response.Body)
+	a.recordViewEvent(r, link, session, models.EndpointFile, path)
+
+	w.Header().Set(RedactionsHeader, strconv.Itoa(redactions))
 	w.Header().Set("Content-Type", "text/plain")
 	w.Write(content)
 }
 
-func ViewerFolderHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) ViewerFolderHandler(w http.ResponseWriter, r *http.Request) {
 	token := strings.TrimPrefix(r.URL.Path, "/view-folder/")
 	if token == "" {
 		http.Error(w, "Missing token", http.StatusBadRequest)
@@ -253,96 +304,142 @@ func ViewerFolderHandler(w http.ResponseWriter, r *http.Request) {
 
 	// fmt.Println("Folder View Token:", token)
 
-	path := r.URL.Query().Get("path")
-
-	dbInstance := config.DB
+	path := pathrules.Clean(r.URL.Query().Get("path"))
 
 	// To look up viewer link
-	var link models.ViewerLink
-	if err := dbInstance.Where("token = ?", token).First(&link).Error; err != nil {
+	link, err := a.Links.ByToken(token)
+	if err != nil {
 		http.Error(w, "Invalid viewer link", http.StatusNotFound)
 		return
 	}
 
-	// To check expiration and view count
-	if time.Now().After(link.ExpiresAt) || (link.MaxViews > 0 && link.ViewCount >= link.MaxViews) {
-		http.Error(w, "Link expired or view limit reached", http.StatusForbidden)
+	// To check expiration
+	if a.now().After(link.ExpiresAt) {
+		a.notifyLinkExpired(link)
+		http.Error(w, "Link expired", http.StatusForbidden)
+		return
+	}
+
+	session, ok := a.requireViewerAccess(w, r, link)
+	if !ok {
 		return
 	}
 
 	// To get the user
-	var user models.User
-	if err := dbInstance.First(&user, link.UserID).Error; err != nil {
+	user, err := a.Users.ByID(link.UserID)
+	if err != nil {
 		http.Error(w, "User not found", http.StatusInternalServerError)
 		return
 	}
 
-	// To build the GitHub API folder URL
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", user.GitHubUsername, link.RepoName, path)
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	req.Header.Set("Authorization", "token "+user.GitHubToken)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	// To refuse folders outside the link's scope
+	scope, err := a.loadViewerScope(r.Context(), user, link)
+	if err != nil {
+		writeGitHubError(w, "Failed to load .privyignore: ", err)
+		return
+	}
+
+	if !scope.allowsDir(path) {
+		http.Error(w, "Folder not found", http.StatusNotFound)
+		return
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil || resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		http.Error(w, fmt.Sprintf("GitHub folder fetch error: %s", body), resp.StatusCode)
+	// To list the folder from GitHub
+	contents, err := a.githubClientFor(user).ListContents(r.Context(), user.GitHubToken, user.GitHubUsername, link.RepoName, path, link.ContentRef())
+	if err != nil {
+		writeGitHubError(w, "GitHub folder fetch error: ", err)
 		return
 	}
-	defer resp.Body.Close()
+	contents = scope.filter(contents)
+
+	a.recordViewEvent(r, link, session, models.EndpointFolder, path)
 
 	// To return the folder content
 	w.Header().Set("Content-Type", "application/json")
-	io.Copy(w, resp.Body)
+	json.NewEncoder(w).Encode(api.NewContentEntries(contents))
 }
 
-func ViewUserInfoHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) ViewUserInfoHandler(w http.ResponseWriter, r *http.Request) {
 	token := strings.TrimPrefix(r.URL.Path, "/view-info/")
 	if token == "" {
 		http.Error(w, "Missing token", http.StatusBadRequest)
 		return
 	}
 
-	var link models.ViewerLink
-	if err := config.DB.Where("token = ?", token).First(&link).Error; err != nil {
+	link, err := a.Links.ByToken(token)
+	if err != nil {
 		http.Error(w, "Invalid or expired link", http.StatusNotFound)
 		return
 	}
 
-	var user models.User
-	if err := config.DB.First(&user, link.UserID).Error; err != nil {
+	if a.now().After(link.ExpiresAt) {
+		a.notifyLinkExpired(link)
+		http.Error(w, "Link expired", http.StatusForbidden)
+		return
+	}
+
+	// To tell the frontend to ask for a password or email, without revealing the repo
+	if _, err := a.viewerSessionFromRequest(r, link); err != nil && requiresViewerSession(link) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(api.ViewerAccessRequired{
+			PasswordRequired: link.HasPassword(),
+			EmailRequired:    link.RequiresEmail(),
+		})
+		return
+	}
+
+	session, ok := a.requireViewerAccess(w, r, link)
+	if !ok {
+		return
+	}
+
+	viewerEmail := ""
+	if session != nil {
+		viewerEmail = session.Email
+	}
+
+	user, err := a.Users.ByID(link.UserID)
+	if err != nil {
 		http.Error(w, "User not found", http.StatusInternalServerError)
 		return
 	}
 
+	a.recordViewEvent(r, link, session, models.EndpointInfo, "")
+
 	// To return the user GitHub username and repo name
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"github_username": user.GitHubUsername,
-		"repo_name":       link.RepoName,
+	json.NewEncoder(w).Encode(api.ViewerInfo{
+		PasswordRequired: link.HasPassword(),
+		EmailRequired:    link.RequiresEmail(),
+		ViewerEmail:      viewerEmail,
+		GitHubUsername:   user.GitHubUsername,
+		RepoName:         link.RepoName,
+		Ref:              link.Ref,
+		CommitSHA:        link.CommitSHA,
+		FollowBranch:     link.FollowBranch,
 	})
 }
 
-func UpdateViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/update-link/")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "❌ Invalid ID", http.StatusBadRequest)
+func (a *App) UpdateViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := a.ownedLink(w, r, authz.Update, strings.TrimPrefix(r.URL.Path, "/update-link/"))
+	if !ok {
 		return
 	}
 
-	var link models.ViewerLink
-
-	db := config.DB
-	if err := db.First(&link, id).Error; err != nil {
-		http.Error(w, "❌ Link not found", http.StatusNotFound)
-		return
-	}
+	var err error
 
 	var payload struct {
-		ExpiresInDays int `json:"expires_in_days"`
-		MaxViews      int `json:"max_views"`
+		ExpiresInDays int       `json:"expires_in_days"`
+		MaxViews      int       `json:"max_views"`
+		AllowPaths    *[]string `json:"allow_paths"` // omitted leaves the rules unchanged
+		DenyPaths     *[]string `json:"deny_paths"`
+
+		DisableRedaction *bool `json:"disable_redaction"`
+
+		Password *string `json:"password"` // "" removes the password
+
+		AccessMode    *string   `json:"access_mode"`
+		AllowedEmails *[]string `json:"allowed_emails"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
@@ -350,48 +447,105 @@ func UpdateViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if payload.AllowPaths != nil {
+		link.AllowPaths = *payload.AllowPaths
+	}
+
+	if payload.DenyPaths != nil {
+		link.DenyPaths = *payload.DenyPaths
+	}
+
+	if payload.DisableRedaction != nil {
+		link.DisableRedaction = *payload.DisableRedaction
+	}
+
+	passwordChanged := false
+	if payload.Password != nil {
+		link.PasswordHash = ""
+		if *payload.Password != "" {
+			link.PasswordHash, err = hashLinkPassword(*payload.Password)
+			if err != nil {
+				http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		passwordChanged = true
+	}
+
+	if payload.AccessMode != nil {
+		passwordChanged = passwordChanged || *payload.AccessMode != link.AccessMode
+		link.AccessMode = *payload.AccessMode
+	}
+
+	if payload.AllowedEmails != nil {
+		link.AllowedEmails = *payload.AllowedEmails
+	}
+
+	link.AllowedEmails, err = validateEmailAccess(link.AccessMode, link.AllowedEmails)
+	if err != nil {
+		http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if link.RequiresEmail() && link.HasPassword() {
+		http.Error(w, "❌ A link can use a password or email verification, not both", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePathRules(link.AllowPaths, link.DenyPaths); err != nil {
+		http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if payload.ExpiresInDays > 0 {
-		link.ExpiresAt = time.Now().Add(time.Duration(payload.ExpiresInDays) * 24 * time.Hour)
+		link.ExpiresAt = a.now().Add(time.Duration(payload.ExpiresInDays) * 24 * time.Hour)
 	}
 
 	if payload.MaxViews > 0 {
 		link.MaxViews = payload.MaxViews
 	}
 
-	if err := db.Save(&link).Error; err != nil {
+	if err := a.Links.Update(link); err != nil {
 		http.Error(w, "❌ Could not update link", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Viewer link updated successfully",
+	// To sign out viewers who got in under the old password or access mode
+	if passwordChanged {
+		a.DB.Where("viewer_link_id = ?", link.ID).Delete(&models.ViewerSession{})
+	}
+
+	a.notifyLinkEvent(link, webhooks.EventLinkUpdated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.LinkUpdated{
+		Message: "Viewer link updated successfully",
+		Link:    api.NewLink(link, a.Config.FrontendURL),
 	})
 }
 
-func DeleteViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/delete-link/")
-	id, err := strconv.Atoi(idStr)
-
-	if err != nil {
-		http.Error(w, "❌ Invalid ID", http.StatusBadRequest)
+func (a *App) DeleteViewerLinkHandler(w http.ResponseWriter, r *http.Request) {
+	link, ok := a.ownedLink(w, r, authz.Delete, strings.TrimPrefix(r.URL.Path, "/delete-link/"))
+	if !ok {
 		return
 	}
 
-	var link models.ViewerLink
-	db := config.DB
-	if err := db.First(&link, id).Error; err != nil {
-		http.Error(w, "❌ Link not found", http.StatusNotFound)
+	if err := a.Links.Delete(link); err != nil {
+		http.Error(w, "❌  Could not delete link", http.StatusInternalServerError)
 		return
 	}
 
-	if err := db.Delete(&link).Error; err != nil {
-		http.Error(w, "❌  Could not delete link", http.StatusInternalServerError)
-		return
+	a.notifyLinkEvent(link, webhooks.EventLinkDeleted)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Message{Message: "Viewer link deleted"})
+}
+
+// To check the allow and deny globs of a link
+func validatePathRules(allow, deny []string) error {
+	if err := pathrules.Validate(allow); err != nil {
+		return err
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Viewer link deleted",
-	})
+	return pathrules.Validate(deny)
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"path"
+
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/pathrules"
+	"github.com/greatdaveo/privycode-server/internal/privyignore"
+)
+
+// viewerScope is everything a viewer of a link may see: the link's own path
+// rules combined with the repository's .privyignore. Every viewer endpoint
+// checks paths through it.
+type viewerScope struct {
+	rules  pathrules.Rules
+	ignore *privyignore.Matcher
+}
+
+// To build the scope of a link, fetching (or reusing) the repo's .privyignore at the link's ref
+func (a *App) loadViewerScope(ctx context.Context, user *models.User, link *models.ViewerLink) (viewerScope, error) {
+	matcher, err := a.privyignore.Get(ctx, a.githubClientFor(user), user.GitHubToken, user.GitHubUsername, link.RepoName, link.ContentRef())
+	if err != nil {
+		return viewerScope{}, err
+	}
+
+	return viewerScope{rules: link.PathRules(), ignore: matcher}, nil
+}
+
+func (s viewerScope) allowsFile(p string) bool {
+	return s.rules.AllowsFile(p) && !s.ignore.Ignored(pathrules.Clean(p), false)
+}
+
+func (s viewerScope) allowsDir(p string) bool {
+	return s.rules.AllowsDir(p) && !s.ignore.Ignored(pathrules.Clean(p), true)
+}
+
+// To drop listing entries outside the scope, so hidden paths are omitted rather than errored.
+// Symlinks are dropped too, since viewers cannot open them (see regularFile).
+func (s viewerScope) filter(entries []github.ContentEntry) []github.ContentEntry {
+	visible := make([]github.ContentEntry, 0, len(entries))
+	for _, entry := range entries {
+		allowed := s.allowsFile(entry.Path)
+		switch entry.Type {
+		case "dir":
+			allowed = s.allowsDir(entry.Path)
+		case "symlink":
+			allowed = false
+		}
+
+		if allowed {
+			visible = append(visible, entry)
+		}
+	}
+
+	return visible
+}
+
+// To check that p is a regular file by finding it in its directory listing.
+// GitHub serves a symlink's target when asked for the link itself, so a
+// symlink inside the scope could otherwise expose a file outside it.
+func (a *App) regularFile(ctx context.Context, user *models.User, link *models.ViewerLink, p string) (bool, error) {
+	dir := path.Dir(p)
+	if dir == "." {
+		dir = ""
+	}
+
+	entries, err := a.githubClientFor(user).ListContents(ctx, user.GitHubToken, user.GitHubUsername, link.RepoName, dir, link.ContentRef())
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if entry.Path == p {
+			return entry.Type == "file", nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// ViewerSessionHeader carries the viewer session for frontends that cannot
+	// use the cookie. New sessions are returned in the same response header.
+	ViewerSessionHeader = "X-Viewer-Session"
+
+	// A viewer session never outlives this, however active it is
+	viewerSessionMaxAge = 12 * time.Hour
+
+	maxFailedUnlocks  = 5
+	unlockLockoutTime = 15 * time.Minute
+	minPasswordLength = 6
+)
+
+// To unlock a password-protected link and start a viewer session (which counts as a view)
+func (a *App) ViewerUnlockHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/view-unlock/")
+	if token == "" {
+		http.Error(w, "❌ Missing token", http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Password == "" {
+		http.Error(w, "❌ Missing password", http.StatusBadRequest)
+		return
+	}
+
+	link, err := a.Links.ByToken(token)
+	if err != nil {
+		http.Error(w, "❌ Invalid or expired link", http.StatusNotFound)
+		return
+	}
+
+	if a.now().After(link.ExpiresAt) {
+		a.notifyLinkExpired(link)
+		http.Error(w, "❌ Link has expired", http.StatusForbidden)
+		return
+	}
+
+	if !link.HasPassword() {
+		http.Error(w, "❌ This link is not password protected", http.StatusBadRequest)
+		return
+	}
+
+	// To refuse attempts while the link is locked out
+	if link.LockedUntil != nil && a.now().Before(*link.LockedUntil) {
+		retryAfter := int(link.LockedUntil.Sub(a.now()).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, "❌ Too many failed attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(payload.Password)); err != nil {
+		a.recordFailedUnlock(link)
+		http.Error(w, "❌ Incorrect password", http.StatusUnauthorized)
+		return
+	}
+
+	if err := a.Links.ResetFailedUnlocks(link); err != nil {
+		a.Logger.Printf("❌ Could not reset failed unlocks for link %d: %v", link.ID, err)
+	}
+
+	sessionToken, session, err := a.startViewerSession(link, "")
+	if errors.Is(err, store.ErrViewLimitReached) {
+		http.Error(w, "❌ View limit reached", http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, "❌ Could not start viewer session", http.StatusInternalServerError)
+		return
+	}
+
+	a.setViewerSessionCookie(w, link, sessionToken, session.ExpiresAt)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.ViewerSession{
+		ViewerSession: sessionToken,
+		ExpiresAt:     session.ExpiresAt,
+	})
+}
+
+// To count a failed unlock atomically and lock the link once the limit is hit
+func (a *App) recordFailedUnlock(link *models.ViewerLink) {
+	if err := a.Links.RecordFailedUnlock(link, maxFailedUnlocks, a.now().Add(unlockLockoutTime)); err != nil {
+		a.Logger.Printf("❌ Could not record failed unlock for link %d: %v", link.ID, err)
+	}
+}
+
+var (
+	errViewerSessionRequired = errors.New("viewer session required")
+)
+
+// To start a viewer session for a link (and the viewer's verified email, if
+// any), returning the raw token. A new session is what counts as a view, so
+// the link's view count is incremented in the same transaction, unless the
+// limit has been reached.
+func (a *App) startViewerSession(link *models.ViewerLink, email string) (string, *models.ViewerSession, error) {
+	rawToken, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := a.now()
+	session := models.ViewerSession{
+		ViewerLinkID: link.ID,
+		TokenHash:    utils.HashToken(rawToken),
+		ExpiresAt:    now.Add(viewerSessionMaxAge),
+		LastSeenAt:   now,
+		Email:        email,
+	}
+
+	// To count the view and create the session together (see LinkStore.AddView)
+	err = a.Links.AddView(link, &session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if link.ViewCount == 1 {
+		a.notifyLinkEvent(link, webhooks.EventLinkFirstOpened)
+		a.notifyOwnerFirstOpened(link)
+	}
+	if link.MaxViews > 0 && link.ViewCount == link.MaxViews {
+		a.notifyLinkEvent(link, webhooks.EventViewLimitReached)
+	}
+
+	return rawToken, &session, nil
+}
+
+// To find the valid viewer session sent with the request, from the header or the link's cookie
+func (a *App) viewerSessionFromRequest(r *http.Request, link *models.ViewerLink) (*models.ViewerSession, error) {
+	rawToken := r.Header.Get(ViewerSessionHeader)
+	if rawToken == "" {
+		if cookie, err := r.Cookie(viewerSessionCookieName(link)); err == nil {
+			rawToken = cookie.Value
+		}
+	}
+
+	if rawToken == "" {
+		return nil, errViewerSessionRequired
+	}
+
+	now := a.now()
+
+	var session models.ViewerSession
+	err := a.DB.
+		Where("token_hash = ? AND viewer_link_id = ? AND expires_at > ? AND last_seen_at > ?",
+			utils.HashToken(rawToken), link.ID, now, now.Add(-a.Config.ViewerIdleTimeout)).
+		First(&session).Error
+	if err != nil {
+		return nil, errViewerSessionRequired
+	}
+
+	// To keep the session alive while the viewer is active
+	a.DB.Model(&session).UpdateColumn("last_seen_at", now)
+
+	return &session, nil
+}
+
+// To check whether viewers need a session (password or verified email) to see the link
+func requiresViewerSession(link *models.ViewerLink) bool {
+	return link.HasPassword() || link.RequiresEmail()
+}
+
+// To admit a viewer to a link, the same way on every viewer endpoint.
+// A request with a live viewer session continues it. Otherwise protected
+// links answer 401 (the session starts at unlock or email verification),
+// and public links start a new session, which counts as a view and is
+// refused with 403 once the view limit is reached. Writes the error
+// response and returns false when the viewer is not admitted.
+func (a *App) requireViewerAccess(w http.ResponseWriter, r *http.Request, link *models.ViewerLink) (*models.ViewerSession, bool) {
+	if session, err := a.viewerSessionFromRequest(r, link); err == nil {
+		return session, true
+	}
+
+	if requiresViewerSession(link) {
+		message := "❌ Password required"
+		if link.RequiresEmail() {
+			message = "❌ Email verification required"
+		}
+		http.Error(w, message, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	sessionToken, session, err := a.startViewerSession(link, "")
+	if errors.Is(err, store.ErrViewLimitReached) {
+		http.Error(w, "❌ View limit reached", http.StatusForbidden)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, "❌ Could not start viewer session", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	a.setViewerSessionCookie(w, link, sessionToken, session.ExpiresAt)
+	w.Header().Set(ViewerSessionHeader, sessionToken)
+
+	return session, true
+}
+
+// To hash a new link password, enforcing the length limits bcrypt can handle
+func hashLinkPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", errors.New("password must be at least 6 characters")
+	}
+	if len(password) > 72 {
+		return "", errors.New("password must be at most 72 bytes")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func viewerSessionCookieName(link *models.ViewerLink) string {
+	return "privycode_viewer_" + link.Token
+}
+
+func (a *App) setViewerSessionCookie(w http.ResponseWriter, link *models.ViewerLink, rawToken string, expiresAt time.Time) {
+	production := a.Config.Production()
+
+	// To allow the frontend on another site to send the cookie with credentialed requests
+	sameSite := http.SameSiteLaxMode
+	if production {
+		sameSite = http.SameSiteNoneMode
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     viewerSessionCookieName(link),
+		Value:    rawToken,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   production,
+		SameSite: sameSite,
+	})
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
+)
+
+// To queue a webhook event about a link for its owner. Failing to queue
+// never fails the request that caused the event.
+func (a *App) notifyLinkEvent(link *models.ViewerLink, event string) {
+	if _, err := webhooks.Enqueue(a.DB, a.now(), link.UserID, event, webhooks.Link(link)); err != nil {
+		a.Logger.Printf("❌ Could not queue %s webhook for link %d: %v", event, link.ID, err)
+	}
+}
+
+// To send link.expired once, the first time anyone notices the link has expired
+func (a *App) notifyLinkExpired(link *models.ViewerLink) {
+	marked, err := a.Links.MarkExpiryNotified(link, a.now())
+	if err != nil || !marked {
+		return
+	}
+
+	a.notifyLinkEvent(link, webhooks.EventLinkExpired)
+}
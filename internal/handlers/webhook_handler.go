@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/greatdaveo/privycode-server/internal/api"
+	"github.com/greatdaveo/privycode-server/internal/authz"
+	"github.com/greatdaveo/privycode-server/internal/middleware"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// To show the user's webhook configuration (the secret is only shown when it is created)
+func (a *App) GetWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhook, ok := a.ownedWebhook(w, user, authz.Read)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.WebhookConfig{
+		Webhook:         api.NewWebhook(webhook),
+		AvailableEvents: webhooks.Events,
+	})
+}
+
+// To create or change the user's webhook
+func (a *App) SaveWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		URL          string    `json:"url"`
+		Events       *[]string `json:"events"` // omitted leaves the subscription unchanged; [] means every event
+		Active       *bool     `json:"active"`
+		RotateSecret bool      `json:"rotate_secret"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "❌ Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := authz.Webhook(a.DB, user, authz.Update)
+	isNew := errors.Is(err, authz.ErrNotFound)
+	if err != nil && !isNew {
+		http.Error(w, "❌ Database error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if isNew {
+		webhook = &models.Webhook{UserID: user.ID, Events: []string{}, Active: true}
+	}
+
+	if req.URL != "" {
+		webhook.URL = req.URL
+	}
+	if err := a.validateWebhookURL(webhook.URL); err != nil {
+		http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Events != nil {
+		if err := webhooks.ValidateEvents(*req.Events); err != nil {
+			http.Error(w, "❌ "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		webhook.Events = *req.Events
+	}
+
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	// To hand out a new signing secret, which is only ever shown in this response
+	secret := ""
+	if isNew || req.RotateSecret {
+		secret, err = utils.GenerateSecureToken()
+		if err != nil {
+			http.Error(w, "❌ Could not generate webhook secret", http.StatusInternalServerError)
+			return
+		}
+		webhook.Secret = secret
+	}
+
+	if err := a.DB.Save(webhook).Error; err != nil {
+		http.Error(w, "❌ Could not save webhook", http.StatusInternalServerError)
+		return
+	}
+
+	response := api.WebhookSaved{Webhook: api.NewWebhook(webhook), Secret: secret}
+
+	w.Header().Set("Content-Type", "application/json")
+	if isNew {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// To remove the user's webhook together with its delivery log
+func (a *App) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhook, ok := a.ownedWebhook(w, user, authz.Delete)
+	if !ok {
+		return
+	}
+
+	err := a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(webhook).Error
+	})
+	if err != nil {
+		http.Error(w, "❌ Could not delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Message{Message: "Webhook deleted"})
+}
+
+// To list recent deliveries of the user's webhook, newest first (?status= and ?limit= optional)
+func (a *App) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxDeliveryLimit {
+			http.Error(w, "❌ limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+	}
+
+	webhook, ok := a.ownedWebhook(w, user, authz.Read)
+	if !ok {
+		return
+	}
+
+	query := a.DB.Where("webhook_id = ?", webhook.ID)
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		http.Error(w, "❌ Failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.NewWebhookDeliveries(deliveries))
+}
+
+// To send a webhook.test event right away and report how the endpoint answered
+func (a *App) TestWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r)
+	if user == nil {
+		http.Error(w, "❌ Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if a.Webhooks == nil {
+		http.Error(w, "❌ Webhooks are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	delivery, err := webhooks.Enqueue(a.DB, a.now(), user.ID, webhooks.EventTest, map[string]string{
+		"message":         "Test event from PrivyCode",
+		"github_username": user.GitHubUsername,
+	})
+	if err != nil {
+		http.Error(w, "❌ Could not queue test event", http.StatusInternalServerError)
+		return
+	}
+	if delivery == nil {
+		http.Error(w, "❌ No webhook configured", http.StatusNotFound)
+		return
+	}
+
+	if err := a.Webhooks.DeliverNow(r.Context(), delivery); err != nil {
+		http.Error(w, "❌ Could not record test delivery", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.NewWebhookDelivery(delivery))
+}
+
+// To load the user's webhook for an action, writing a 404 if they have none
+func (a *App) ownedWebhook(w http.ResponseWriter, user *models.User, action authz.Action) (*models.Webhook, bool) {
+	webhook, err := authz.Webhook(a.DB, user, action)
+	if errors.Is(err, authz.ErrNotFound) {
+		http.Error(w, "❌ No webhook configured", http.StatusNotFound)
+		return nil, false
+	} else if err != nil {
+		http.Error(w, "❌ Database error: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// To accept only absolute http(s) webhook URLs to public hosts, and only
+// https in production. The dispatcher checks resolved addresses again.
+func (a *App) validateWebhookURL(raw string) error {
+	if raw == "" {
+		return errors.New("url is required")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+
+	if parsed.Scheme != "https" && a.Config.Production() {
+		return errors.New("url must use https")
+	}
+
+	if webhooks.BlockedHost(parsed.Hostname()) {
+		return errors.New("url must point at a public host")
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/notify"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+const batchSize = 500
+
+// Retention is how long deleted links and view events are kept
+// (LINK_RETENTION_DAYS and VIEW_EVENT_RETENTION_DAYS)
+type Retention struct {
+	DeletedLinks time.Duration
+	ViewEvents   time.Duration
+}
+
+// To build the link lifecycle jobs, timed by clock and reporting to logger.
+// Owner emails are skipped when notifier is nil.
+func Maintenance(db *gorm.DB, notifier *notify.Notifier, retention Retention, clock func() time.Time, logger *log.Logger) []Job {
+	jobs := []Job{
+		{
+			Name:     "mark-expired-links",
+			Interval: 5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				return MarkExpiredLinks(db, clock(), logger)
+			},
+		},
+		{
+			Name:     "purge-deleted-links",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				return PurgeDeletedLinks(db, clock().Add(-retention.DeletedLinks), logger)
+			},
+		},
+		{
+			Name:     "purge-view-events",
+			Interval: time.Hour,
+			Run: func(ctx context.Context) error {
+				return PurgeViewEvents(db, clock().Add(-retention.ViewEvents), logger)
+			},
+		},
+	}
+
+	if notifier != nil {
+		jobs = append(jobs,
+			Job{
+				Name:     "expiry-reminders",
+				Interval: 15 * time.Minute,
+				Run: func(ctx context.Context) error {
+					_, err := notifier.SendExpiryReminders(ctx, clock())
+					return err
+				},
+			},
+			Job{
+				Name:     "weekly-digests",
+				Interval: time.Hour,
+				Run: func(ctx context.Context) error {
+					_, err := notifier.SendWeeklyDigests(ctx, clock())
+					return err
+				},
+			},
+		)
+	}
+
+	return jobs
+}
+
+// To mark links that have expired: their viewer sessions end and the owner's
+// webhook gets link.expired, even if no viewer ever comes back to the link
+func MarkExpiredLinks(db *gorm.DB, now time.Time, logger *log.Logger) error {
+	var expired []models.ViewerLink
+	err := db.Where("expires_at <= ? AND expiry_notified_at IS NULL", now).Limit(batchSize).Find(&expired).Error
+	if err != nil {
+		return err
+	}
+
+	links := store.NewGormLinkStore(db)
+	count := 0
+	for i := range expired {
+		link := &expired[i]
+
+		// To mark each link only once, even if a viewer request got to it first
+		marked, err := links.MarkExpiryNotified(link, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			continue
+		}
+
+		if err := db.Where("viewer_link_id = ?", link.ID).Delete(&models.ViewerSession{}).Error; err != nil {
+			return err
+		}
+
+		if _, err := webhooks.Enqueue(db, now, link.UserID, webhooks.EventLinkExpired, webhooks.Link(link)); err != nil {
+			logger.Printf("❌ Could not queue link.expired webhook for link %d: %v", link.ID, err)
+		}
+		count++
+	}
+
+	if count > 0 {
+		logger.Printf("🧹 Marked %d expired links", count)
+	}
+
+	return nil
+}
+
+// To permanently delete links soft-deleted before the cutoff, with
+// everything that belongs to them
+func PurgeDeletedLinks(db *gorm.DB, cutoff time.Time, logger *log.Logger) error {
+	var ids []uint
+	err := db.Unscoped().Model(&models.ViewerLink{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Limit(batchSize).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	if err := PurgeLinks(db, ids); err != nil {
+		return err
+	}
+
+	logger.Printf("🧹 Purged %d deleted links", len(ids))
+	return nil
+}
+
+// To permanently delete links, deleted or not, with their view events,
+// sign-in links and viewer sessions
+func PurgeLinks(db *gorm.DB, ids []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.ViewEvent{}, &models.ViewerMagicLink{}, &models.ViewerSession{}} {
+			if err := tx.Unscoped().Where("viewer_link_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&models.ViewerLink{}).Error
+	})
+}
+
+// To delete view events recorded before the cutoff
+func PurgeViewEvents(db *gorm.DB, cutoff time.Time, logger *log.Logger) error {
+	result := db.Where("created_at < ?", cutoff).Delete(&models.ViewEvent{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		logger.Printf("🧹 Purged %d old view events", result.RowsAffected)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package jobs runs periodic maintenance inside the server process. Every
+// replica runs a Scheduler; a PostgreSQL advisory lock per job makes sure
+// only one of them runs a given job at a time, and the job_runs table makes
+// sure it runs once per interval across all of them.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job is a task the scheduler runs every Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	DB     *gorm.DB
+	Jobs   []Job
+	Clock  func() time.Time
+	Logger *log.Logger
+
+	// local stands in for the advisory lock on databases without one (SQLite),
+	// where only a single server can be running anyway
+	local sync.Map
+}
+
+func NewScheduler(db *gorm.DB, clock func() time.Time, logger *log.Logger, jobs ...Job) *Scheduler {
+	return &Scheduler{DB: db, Jobs: jobs, Clock: clock, Logger: logger}
+}
+
+// To run every job on its own ticker until ctx is done. Each job runs once right away.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.Jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// To run a job now if it is due and no other replica is running it, logging
+// failures. Returns false if the job was skipped because another replica
+// holds the lock or has already run it this interval.
+func (s *Scheduler) RunOnce(ctx context.Context, job Job) bool {
+	unlock, acquired, err := s.lock(ctx, job.Name)
+	if err != nil {
+		s.Logger.Printf("❌ Job %s: could not take lock: %v", job.Name, err)
+		return false
+	}
+	if !acquired {
+		return false
+	}
+	defer unlock()
+
+	// To claim this interval's run while holding the lock, so replicas whose
+	// tickers fire later in the interval skip it
+	started := s.Clock()
+	due, err := s.claimRun(job, started)
+	if err != nil {
+		s.Logger.Printf("❌ Job %s: could not record run: %v", job.Name, err)
+		return false
+	}
+	if !due {
+		return false
+	}
+
+	if err := job.Run(ctx); err != nil {
+		s.Logger.Printf("❌ Job %s failed after %s: %v", job.Name, time.Since(started).Round(time.Millisecond), err)
+	}
+
+	return true
+}
+
+// To record that job starts at now, unless it already ran within its
+// interval. A tenth of the interval is allowed for ticker drift, so the
+// replica that ran the job last time is due again on its next tick.
+func (s *Scheduler) claimRun(job Job, now time.Time) (bool, error) {
+	var last models.JobRun
+	err := s.DB.Where("name = ?", job.Name).Take(&last).Error
+	if err == nil && now.Sub(last.LastRunAt) < job.Interval-job.Interval/10 {
+		return false, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	run := models.JobRun{Name: job.Name, LastRunAt: now}
+	err = s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_run_at"}),
+	}).Create(&run).Error
+
+	return err == nil, err
+}
+
+// To take the job's lock without waiting. On PostgreSQL this is a session
+// advisory lock, held on a dedicated connection until unlock is called.
+func (s *Scheduler) lock(ctx context.Context, name string) (unlock func(), acquired bool, err error) {
+	if s.DB.Dialector.Name() != "postgres" {
+		mu, _ := s.local.LoadOrStore(name, &sync.Mutex{})
+		if !mu.(*sync.Mutex).TryLock() {
+			return nil, false, nil
+		}
+		return mu.(*sync.Mutex).Unlock, true, nil
+	}
+
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return nil, false, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := lockKey(name)
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return nil, false, err
+	}
+
+	return func() { s.releaseLock(conn, key) }, true, nil
+}
+
+func (s *Scheduler) releaseLock(conn *sql.Conn, key int64) {
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+		s.Logger.Printf("❌ Could not release job lock %d: %v", key, err)
+	}
+}
+
+// To derive a stable advisory lock key from a job name
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("privycode-job:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/testutil"
+)
+
+func TestJobRunsOncePerIntervalAcrossReplicas(t *testing.T) {
+	db := testutil.DB(t)
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	logger := log.New(io.Discard, "", 0)
+
+	runs := 0
+	job := Job{Name: "count", Interval: time.Hour, Run: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}
+
+	// To stand in for two replicas, each with its own in-process lock
+	replicas := []*Scheduler{NewScheduler(db, clock, logger, job), NewScheduler(db, clock, logger, job)}
+
+	tick := func() {
+		for _, replica := range replicas {
+			replica.RunOnce(context.Background(), job)
+		}
+	}
+
+	tick()
+	if runs != 1 {
+		t.Fatalf("%d runs after both replicas ticked, want 1", runs)
+	}
+
+	now = now.Add(30 * time.Minute)
+	tick()
+	if runs != 1 {
+		t.Fatalf("%d runs half an interval later, want 1", runs)
+	}
+
+	// To allow a tick that comes a little early
+	now = now.Add(25 * time.Minute)
+	tick()
+	if runs != 2 {
+		t.Errorf("%d runs an interval later, want 2", runs)
+	}
+}
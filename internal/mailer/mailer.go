@@ -0,0 +1,46 @@
+// Package mailer sends transactional email. Deployments pick an
+// implementation with MAILER: "smtp" for real delivery, "log" (the default)
+// to print messages, or "file" to write them to MAIL_DIR during development.
+// Tests can use a CaptureMailer.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // plain text
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config picks and configures a mailer
+type Config struct {
+	Kind string // MAILER: "smtp", "log" or "file"
+	Dir  string // MAIL_DIR, for "file"
+	SMTP SMTPConfig
+}
+
+// To build the mailer the config picks; the log mailer writes to logger
+func New(c Config, logger *log.Logger) (Mailer, error) {
+	switch c.Kind {
+	case "", "log":
+		return NewLogMailer(logger), nil
+	case "file":
+		dir := c.Dir
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		return NewFileMailer(dir), nil
+	case "smtp":
+		return NewSMTPMailer(c.SMTP)
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q (expected smtp, log or file)", c.Kind)
+	}
+}
@@ -4,35 +4,53 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/greatdaveo/privycode-server/config"
 	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+	"gorm.io/gorm"
 )
 
 type contextKey string
 
-const userCtxKey = contextKey("user")
+const (
+	userCtxKey    = contextKey("user")
+	sessionCtxKey = contextKey("session")
+)
 
-// To extract user from Authorization header
-func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
+// To extract user from the PrivyCode session token in the Authorization header,
+// looking the session up in db; clock decides whether it has expired
+func AuthMiddleware(db *gorm.DB, clock func() time.Time) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
 
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "❌ Unauthorized: Missing token", http.StatusUnauthorized)
-			return
-		}
+			if !strings.HasPrefix(authHeader, "Bearer ") {
+				http.Error(w, "❌ Unauthorized: Missing token", http.StatusUnauthorized)
+				return
+			}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
+			token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		var user models.User
-		if err := config.DB.Where("git_hub_token = ?", token).First(&user).Error; err != nil {
-			http.Error(w, "❌ Unauthorized: Invalid token", http.StatusUnauthorized)
-			return
-		}
+			now := clock()
 
-		ctx := context.WithValue(r.Context(), userCtxKey, &user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+			var session models.Session
+			err := db.Preload("User").
+				Where("token_hash = ? AND expires_at > ?", utils.HashToken(token), now).
+				First(&session).Error
+			if err != nil {
+				http.Error(w, "❌ Unauthorized: Invalid or expired session", http.StatusUnauthorized)
+				return
+			}
+
+			// To track when the session was last used
+			db.Model(&session).UpdateColumn("last_used_at", now)
+
+			user := session.User
+			ctx := context.WithValue(r.Context(), userCtxKey, &user)
+			ctx = context.WithValue(ctx, sessionCtxKey, &session)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
 	}
 }
 
@@ -45,3 +63,13 @@ func GetUserFromContext(r *http.Request) *models.User {
 
 	return user
 }
+
+// To extract the current session from the context
+func GetSessionFromContext(r *http.Request) *models.Session {
+	session, ok := r.Context().Value(sessionCtxKey).(*models.Session)
+	if !ok {
+		return nil
+	}
+
+	return session
+}
@@ -2,22 +2,24 @@ package middleware
 
 import "net/http"
 
-func WithCORS(next http.Handler) http.Handler {
+// To allow credentialed cross-origin requests from the given origins only
+func WithCORS(next http.Handler, origins []string) http.Handler {
+	allowedOrigins := map[string]bool{}
+	for _, origin := range origins {
+		allowedOrigins[origin] = true
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
-		allowedOrigins := map[string]bool{
-			"http://localhost:5173":     true,
-			"https://privycode.com":     true,
-			"https://www.privycode.com": true,
-		}
-
 		if allowedOrigins[origin] {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
+		w.Header().Add("Vary", "Origin")
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Viewer-Session, X-Viewer-Referrer")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "X-PrivyCode-Redactions, X-Viewer-Session")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithCORSAllowsOnlyConfiguredOrigins(t *testing.T) {
+	handler := WithCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		[]string{"https://app.example.com", "https://partner.example.com"})
+
+	for origin, want := range map[string]string{
+		"https://app.example.com":     "https://app.example.com",
+		"https://partner.example.com": "https://partner.example.com",
+		"https://privycode.com":       "",
+		"http://localhost:5173":       "",
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/dashboard", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("origin %s: Access-Control-Allow-Origin = %q, want %q", origin, got, want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// JobRun records when a background job last started, on any replica
+type JobRun struct {
+	Name      string    `gorm:"primaryKey"`
+	LastRunAt time.Time `gorm:"not null"`
+}
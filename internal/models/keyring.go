@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/greatdaveo/privycode-server/internal/secrets"
+	"gorm.io/gorm"
+)
+
+const keyringSetting = "privycode:keyring"
+
+// To attach the keyring that encrypts GitHub tokens and webhook secrets to
+// db. The model hooks read it from there, so every query, transaction,
+// preload and batch started from the returned DB can encrypt and decrypt.
+func WithKeyring(db *gorm.DB, keyring *secrets.Keyring) *gorm.DB {
+	return db.Set(keyringSetting, keyring).Session(&gorm.Session{})
+}
+
+// To find the keyring attached by WithKeyring
+func Keyring(db *gorm.DB) (*secrets.Keyring, error) {
+	if value, ok := db.Get(keyringSetting); ok {
+		if keyring, ok := value.(*secrets.Keyring); ok && keyring != nil {
+			return keyring, nil
+		}
+	}
+
+	return nil, secrets.ErrNoKeyring
+}
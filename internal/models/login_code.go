@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginCode is handed to the frontend in the OAuth callback redirect and
+// exchanged once, shortly after, for a session token. Only the SHA-256 hash
+// of the code is stored, so the session token never appears in a URL.
+type LoginCode struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE"`
+	CodeHash  string    `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuthState is a pending GitHub login. It is created by the login handler
+// and consumed exactly once by the callback handler.
+type OAuthState struct {
+	gorm.Model
+	StateHash    string `gorm:"not null;uniqueIndex" json:"-"`
+	CodeVerifier string `gorm:"not null" json:"-"`
+	ReturnTo     string
+	ExpiresAt    time.Time `gorm:"not null"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a PrivyCode login session. Only the SHA-256 hash of the
+// opaque session token is stored; the raw token is handed to the client once.
+type Session struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index"`
+	User       User      `gorm:"constraint:OnDelete:CASCADE"`
+	TokenHash  string    `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	LastUsedAt time.Time
+	UserAgent  string
+}
@@ -1,11 +1,72 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
-	Email          string       `gorm:"unique;not null"`
-	GitHubUsername string       `gorm:"unique;not null"`
-	GitHubToken    string       `gorm:"not null"`
-	ViewerLinks    []ViewerLink `gorm:"foreignKey:UserID"`
+	Email          string `gorm:"unique;not null"`
+	GitHubUsername string `gorm:"unique;not null"`
+
+	// GitHubAPIURL is the REST API of the GitHub host that issued the token
+	// (empty means the deployment default)
+	GitHubAPIURL string
+
+	// GitHubToken is the decrypted access token. It is never written to the
+	// database; BeforeSave and AfterFind translate it to and from the
+	// encrypted columns below.
+	GitHubToken           string       `gorm:"-" json:"-"`
+	GitHubTokenCiphertext string       `gorm:"not null;default:''" json:"-"`
+	GitHubTokenKeyID      string       `gorm:"not null;default:''" json:"-"`
+	ViewerLinks           []ViewerLink `gorm:"foreignKey:UserID"`
+
+	// Email notifications the user opted into (see package notify)
+	NotifyFirstOpened bool `gorm:"not null;default:false"`
+	NotifyExpiring    bool `gorm:"not null;default:false"`
+	WeeklyDigest      bool `gorm:"not null;default:false"`
+	LastDigestAt      *time.Time
+}
+
+// To encrypt the GitHub token under the active key before every write
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.GitHubToken == "" {
+		return nil
+	}
+
+	keyring, err := Keyring(tx)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, keyID, err := keyring.Encrypt(u.GitHubToken)
+	if err != nil {
+		return err
+	}
+
+	u.GitHubTokenCiphertext = ciphertext
+	u.GitHubTokenKeyID = keyID
+	return nil
+}
+
+// To decrypt the GitHub token whenever a user is loaded
+func (u *User) AfterFind(tx *gorm.DB) error {
+	if u.GitHubTokenCiphertext == "" {
+		return nil
+	}
+
+	keyring, err := Keyring(tx)
+	if err != nil {
+		return err
+	}
+
+	token, err := keyring.Decrypt(u.GitHubTokenCiphertext, u.GitHubTokenKeyID)
+	if err != nil {
+		return err
+	}
+
+	u.GitHubToken = token
+	return nil
 }
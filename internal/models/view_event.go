@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Viewer endpoints recorded in ViewEvent.Endpoint
+const (
+	EndpointRepo   = "repo"
+	EndpointFolder = "folder"
+	EndpointFile   = "file"
+	EndpointInfo   = "info"
+)
+
+// ViewEvent records one request to a viewer endpoint. Events are append-only,
+// so there is no soft delete.
+type ViewEvent struct {
+	ID              uint      `gorm:"primarykey"`
+	CreatedAt       time.Time `gorm:"index"`
+	ViewerLinkID    uint      `gorm:"not null;index"`
+	Endpoint        string    `gorm:"not null"`
+	Path            string
+	IPHash          string `json:"-"`
+	UserAgent       string
+	ViewerSessionID *uint `gorm:"index"`
+	ViewerEmail     string
+	Referrer        string // host the viewer came from, e.g. "mail.google.com"
+}
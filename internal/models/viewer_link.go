@@ -1,18 +1,117 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/greatdaveo/privycode-server/internal/pathrules"
 	"gorm.io/gorm"
 )
 
+// Access modes of a viewer link
+const (
+	AccessPublic = "public" // anyone holding the token
+	AccessEmail  = "email"  // viewers verify their email with a magic link first
+)
+
 type ViewerLink struct {
 	gorm.Model
+
+	// PublicID identifies the link in management routes and API output;
+	// the sequential ID never leaves the server
+	PublicID string `gorm:"size:36;uniqueIndex" json:"id"`
+
 	RepoName  string    `gorm:"not null" json:"repo_name"`
-	UserID    uint      `gorm:"not null" json:"user_id"`
-	User      User      `gorm:"constraint:OnDelete:CASCADE"`
-	Token     string    `gorm:"not null;unique" json:"token"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Token     string    `gorm:"not null;uniqueIndex" json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 	MaxViews  int       `json:"max_views"`
 	ViewCount int       `json:"view_count"`
+
+	// Ref is the branch, tag or SHA the owner asked for; CommitSHA is what it
+	// resolved to when the link was created. Unless FollowBranch is set,
+	// viewers always see CommitSHA.
+	Ref          string `json:"ref"`
+	CommitSHA    string `json:"commit_sha"`
+	FollowBranch bool   `json:"follow_branch"`
+
+	// AllowPaths and DenyPaths scope the link to part of the repository
+	// (see pathrules.Rules). An empty AllowPaths exposes the whole repo.
+	AllowPaths []string `gorm:"type:text;serializer:json" json:"allow_paths"`
+	DenyPaths  []string `gorm:"type:text;serializer:json" json:"deny_paths"`
+
+	// DisableRedaction serves files without masking likely secrets
+	DisableRedaction bool `json:"disable_redaction"`
+
+	// PasswordHash is the bcrypt hash of the optional link password.
+	// FailedUnlocks and LockedUntil throttle guessing.
+	PasswordHash  string     `json:"-"`
+	FailedUnlocks int        `gorm:"not null;default:0" json:"-"`
+	LockedUntil   *time.Time `json:"-"`
+
+	// AccessMode is AccessPublic or AccessEmail. AllowedEmails optionally
+	// restricts email access to addresses ("jane@acme.com") or domains ("@acme.com").
+	AccessMode    string   `gorm:"not null;default:'public'" json:"access_mode"`
+	AllowedEmails []string `gorm:"type:text;serializer:json" json:"allowed_emails"`
+
+	// ExpiryNotifiedAt is when the link was marked expired (by a viewer
+	// request or the maintenance job) and link.expired was sent.
+	// ExpiryReminderSentAt records that the owner was emailed before it expired.
+	ExpiryNotifiedAt     *time.Time `json:"-"`
+	ExpiryReminderSentAt *time.Time `json:"-"`
+}
+
+// To give every new link an opaque public ID
+func (l *ViewerLink) BeforeCreate(tx *gorm.DB) error {
+	if l.PublicID == "" {
+		l.PublicID = uuid.NewString()
+	}
+
+	return nil
+}
+
+// To check whether viewers must unlock the link with a password
+func (l *ViewerLink) HasPassword() bool {
+	return l.PasswordHash != ""
+}
+
+// To check whether viewers must verify their email before viewing
+func (l *ViewerLink) RequiresEmail() bool {
+	return l.AccessMode == AccessEmail
+}
+
+// To check whether an email may request access to an email-verified link
+func (l *ViewerLink) EmailAllowed(email string) bool {
+	if len(l.AllowedEmails) == 0 {
+		return true
+	}
+
+	email = strings.ToLower(email)
+	for _, allowed := range l.AllowedEmails {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if strings.HasPrefix(allowed, "@") && strings.HasSuffix(email, allowed) {
+			return true
+		}
+		if email == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// To get the path rules that scope what viewers of this link can see
+func (l *ViewerLink) PathRules() pathrules.Rules {
+	return pathrules.Rules{Allow: l.AllowPaths, Deny: l.DenyPaths}
+}
+
+// To get the ref passed to GitHub contents calls (empty means the default branch)
+func (l *ViewerLink) ContentRef() string {
+	if l.FollowBranch && l.Ref != "" {
+		return l.Ref
+	}
+
+	return l.CommitSHA
 }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ViewerMagicLink is a one-time sign-in code emailed to a viewer of an
+// email-verified link. Only the SHA-256 hash of the code is stored.
+type ViewerMagicLink struct {
+	gorm.Model
+	ViewerLinkID uint       `gorm:"not null;index"`
+	ViewerLink   ViewerLink `gorm:"constraint:OnDelete:CASCADE"`
+	Email        string     `gorm:"not null"`
+	TokenHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time  `gorm:"not null"`
+	UsedAt       *time.Time
+}
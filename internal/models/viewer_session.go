@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ViewerSession is one visit to a viewer link; each new session counts as a
+// view. Sessions on protected links start when the viewer unlocks the link
+// with its password or verifies their email. A session ends after an idle
+// timeout or at ExpiresAt. Only the SHA-256 hash of the session token is stored.
+type ViewerSession struct {
+	gorm.Model
+	ViewerLinkID uint       `gorm:"not null;index"`
+	ViewerLink   ViewerLink `gorm:"constraint:OnDelete:CASCADE"`
+	TokenHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time  `gorm:"not null"`
+	LastSeenAt   time.Time  `gorm:"index"`
+
+	// Email is the verified address of the viewer, for email-verified links
+	Email string
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook is where a user wants to be told about activity on their viewer
+// links. Payloads are signed with Secret, which is stored encrypted like the
+// GitHub token.
+type Webhook struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	User   User   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	URL    string `gorm:"not null" json:"url"`
+
+	// Events the user subscribed to; empty means every event
+	Events []string `gorm:"type:text;serializer:json" json:"events"`
+	Active bool     `gorm:"not null;default:true" json:"active"`
+
+	Secret           string `gorm:"-" json:"-"`
+	SecretCiphertext string `gorm:"not null;default:''" json:"-"`
+	SecretKeyID      string `gorm:"not null;default:''" json:"-"`
+}
+
+// To tell whether the webhook should receive an event
+func (wh *Webhook) Subscribed(event string) bool {
+	if !wh.Active {
+		return false
+	}
+
+	if len(wh.Events) == 0 {
+		return true
+	}
+
+	for _, subscribed := range wh.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// To encrypt the signing secret under the active key before every write
+func (wh *Webhook) BeforeSave(tx *gorm.DB) error {
+	if wh.Secret == "" {
+		return nil
+	}
+
+	keyring, err := Keyring(tx)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, keyID, err := keyring.Encrypt(wh.Secret)
+	if err != nil {
+		return err
+	}
+
+	wh.SecretCiphertext = ciphertext
+	wh.SecretKeyID = keyID
+	return nil
+}
+
+// To decrypt the signing secret whenever a webhook is loaded
+func (wh *Webhook) AfterFind(tx *gorm.DB) error {
+	if wh.SecretCiphertext == "" {
+		return nil
+	}
+
+	keyring, err := Keyring(tx)
+	if err != nil {
+		return err
+	}
+
+	secret, err := keyring.Decrypt(wh.SecretCiphertext, wh.SecretKeyID)
+	if err != nil {
+		return err
+	}
+
+	wh.Secret = secret
+	return nil
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // gave up after the last retry
+)
+
+// WebhookDelivery is one event queued for a webhook, and the log of trying
+// to deliver it. Pending deliveries are retried with exponential backoff
+// until they succeed or run out of attempts.
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID uint    `gorm:"not null;index" json:"webhook_id"`
+	Webhook   Webhook `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	UUID      string  `gorm:"not null;uniqueIndex" json:"delivery_id"`
+	Event     string  `gorm:"not null" json:"event"`
+	Payload   string  `gorm:"type:text;not null" json:"payload"`
+
+	Status         string     `gorm:"not null;default:'pending';index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
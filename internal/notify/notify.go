@@ -0,0 +1,261 @@
+// Package notify emails link owners about their viewer links: when a link
+// is opened for the first time, when it is about to expire, and a weekly
+// digest of activity. Each email is opt-in through the user's preferences.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/mailer"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// ExpiryWarning is how long before expiry the reminder is sent
+	ExpiryWarning = 24 * time.Hour
+
+	// DigestInterval is how often a user gets the digest
+	DigestInterval = 7 * 24 * time.Hour
+)
+
+// Notifier sends owner notifications through Mailer. FrontendURL is where
+// emails point owners to manage their links; failed sends go to Logger.
+type Notifier struct {
+	DB          *gorm.DB
+	Mailer      mailer.Mailer
+	FrontendURL string
+	Logger      *log.Logger
+}
+
+func New(db *gorm.DB, m mailer.Mailer, frontendURL string, logger *log.Logger) *Notifier {
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+
+	return &Notifier{DB: db, Mailer: m, FrontendURL: strings.TrimRight(frontendURL, "/"), Logger: logger}
+}
+
+// To tell the owner their link was opened for the first time, if they asked for it
+func (n *Notifier) LinkFirstOpened(ctx context.Context, link *models.ViewerLink) error {
+	var owner models.User
+	if err := n.DB.First(&owner, link.UserID).Error; err != nil {
+		return err
+	}
+
+	if !owner.NotifyFirstOpened {
+		return nil
+	}
+
+	return n.Mailer.Send(ctx, mailer.Message{
+		To:      owner.Email,
+		Subject: fmt.Sprintf("Your PrivyCode link to %s was opened", link.RepoName),
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone just opened your link to %s for the first time.\n\n"+
+			"See who is looking on your dashboard:\n%s/dashboard\n",
+			owner.GitHubUsername, link.RepoName, n.FrontendURL),
+	})
+}
+
+// To remind owners about links expiring within ExpiryWarning of now, once per link,
+// returning how many reminders were sent
+func (n *Notifier) SendExpiryReminders(ctx context.Context, now time.Time) (int, error) {
+	var links []models.ViewerLink
+	err := n.DB.
+		Joins("JOIN users ON users.id = viewer_links.user_id").
+		Where("users.notify_expiring = ? AND users.deleted_at IS NULL", true).
+		Where("viewer_links.expires_at > ? AND viewer_links.expires_at <= ?", now, now.Add(ExpiryWarning)).
+		Where("viewer_links.expiry_reminder_sent_at IS NULL").
+		Preload("User").
+		Find(&links).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range links {
+		link := &links[i]
+
+		// To claim the reminder first, so two servers never both send it
+		result := n.DB.Model(&models.ViewerLink{}).
+			Where("id = ? AND expiry_reminder_sent_at IS NULL", link.ID).
+			UpdateColumn("expiry_reminder_sent_at", now)
+		if result.Error != nil {
+			return sent, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		err := n.Mailer.Send(ctx, mailer.Message{
+			To:      link.User.Email,
+			Subject: fmt.Sprintf("Your PrivyCode link to %s expires soon", link.RepoName),
+			Body: fmt.Sprintf("Hi %s,\n\n"+
+				"Your link to %s expires on %s (%d of %s views used).\n\n"+
+				"Extend it from your dashboard if it should stay open:\n%s/dashboard\n",
+				link.User.GitHubUsername, link.RepoName, link.ExpiresAt.UTC().Format("Mon, 02 Jan 2006 15:04 MST"),
+				link.ViewCount, viewLimit(link), n.FrontendURL),
+		})
+		if err != nil {
+			n.Logger.Printf("❌ Failed to send expiry reminder for link %d: %v", link.ID, err)
+
+			// To release the claim so the next run retries the reminder
+			release := n.DB.Model(&models.ViewerLink{}).
+				Where("id = ?", link.ID).
+				UpdateColumn("expiry_reminder_sent_at", nil)
+			if release.Error != nil {
+				return sent, release.Error
+			}
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// To send the weekly digest to every user who opted in and has not had one
+// for DigestInterval, returning how many digests were sent
+func (n *Notifier) SendWeeklyDigests(ctx context.Context, now time.Time) (int, error) {
+	cutoff := now.Add(-DigestInterval)
+
+	var users []models.User
+	err := n.DB.
+		Where("weekly_digest = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", true, cutoff).
+		Find(&users).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range users {
+		user := &users[i]
+
+		// To claim this week's digest first, so two servers never both send it
+		result := n.DB.Model(&models.User{}).
+			Where("id = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)", user.ID, cutoff).
+			UpdateColumn("last_digest_at", now)
+		if result.Error != nil {
+			return sent, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		digest, err := n.buildDigest(user, cutoff, now)
+		if err != nil {
+			return sent, errors.Join(err, n.releaseDigest(user))
+		}
+
+		if err := n.Mailer.Send(ctx, digest); err != nil {
+			n.Logger.Printf("❌ Failed to send weekly digest to user %d: %v", user.ID, err)
+			if err := n.releaseDigest(user); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// To give back a claimed digest that was not sent, so the next run retries it
+func (n *Notifier) releaseDigest(user *models.User) error {
+	return n.DB.Model(&models.User{}).
+		Where("id = ?", user.ID).
+		UpdateColumn("last_digest_at", user.LastDigestAt).Error
+}
+
+type linkActivity struct {
+	ViewerLinkID uint
+	Count        int
+}
+
+// To write the digest from the same links the dashboard shows, with each
+// link's views (viewer sessions) and requests since the last digest
+func (n *Notifier) buildDigest(user *models.User, since, now time.Time) (mailer.Message, error) {
+	var links []models.ViewerLink
+	if err := n.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&links).Error; err != nil {
+		return mailer.Message{}, err
+	}
+
+	views, err := n.countByLink(&models.ViewerSession{}, user.ID, since)
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	requests, err := n.countByLink(&models.ViewEvent{}, user.ID, since)
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nHere is what happened to your PrivyCode links this week.\n\n", user.GitHubUsername)
+
+	if len(links) == 0 {
+		body.WriteString("You have no viewer links yet.\n")
+	}
+
+	totalViews := 0
+	for i := range links {
+		link := &links[i]
+		totalViews += views[link.ID]
+
+		status := "active"
+		switch {
+		case now.After(link.ExpiresAt):
+			status = "expired"
+		case link.MaxViews > 0 && link.ViewCount >= link.MaxViews:
+			status = "view limit reached"
+		}
+
+		fmt.Fprintf(&body, "- %s: %d new views, %d requests (%d of %s views used, %s)\n",
+			link.RepoName, views[link.ID], requests[link.ID], link.ViewCount, viewLimit(link), status)
+	}
+
+	fmt.Fprintf(&body, "\nManage your links:\n%s/dashboard\n", n.FrontendURL)
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Your PrivyCode week: %d new views", totalViews),
+		Body:    body.String(),
+	}, nil
+}
+
+// To count rows of a per-link table (viewer sessions or view events) created
+// since a time, for all of a user's links. Soft-deleted rows count too:
+// sessions are deleted when a link expires or is re-secured, but each was
+// still a view.
+func (n *Notifier) countByLink(model any, userID uint, since time.Time) (map[uint]int, error) {
+	var rows []linkActivity
+	err := n.DB.Unscoped().Model(model).
+		Select("viewer_link_id, COUNT(*) AS count").
+		Where("created_at >= ? AND viewer_link_id IN (?)", since,
+			n.DB.Model(&models.ViewerLink{}).Select("id").Where("user_id = ?", userID)).
+		Group("viewer_link_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.ViewerLinkID] = row.Count
+	}
+
+	return counts, nil
+}
+
+func viewLimit(link *models.ViewerLink) string {
+	if link.MaxViews <= 0 {
+		return "unlimited"
+	}
+
+	return fmt.Sprint(link.MaxViews)
+}
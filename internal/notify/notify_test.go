@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/mailer"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/testutil"
+)
+
+func newTestNotifier(t *testing.T) (*Notifier, *mailer.CaptureMailer, *models.User) {
+	t.Helper()
+
+	db := testutil.DB(t)
+	owner := testutil.User(t, db, "owner")
+	if err := db.Model(owner).Updates(map[string]any{"notify_expiring": true, "weekly_digest": true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	capture := mailer.NewCaptureMailer()
+	return New(db, capture, "https://app.example.com", log.New(io.Discard, "", 0)), capture, owner
+}
+
+func TestExpiryReminderIsSentOnce(t *testing.T) {
+	n, capture, owner := newTestNotifier(t)
+	testutil.Link(t, n.DB, owner, "portfolio", 0)
+
+	// To put the link, which expires in 24 hours, inside the warning window
+	now := time.Now().Add(time.Hour)
+
+	for run, want := range []int{1, 0} {
+		sent, err := n.SendExpiryReminders(context.Background(), now)
+		if err != nil || sent != want {
+			t.Fatalf("run %d sent %d, %v; want %d", run+1, sent, err, want)
+		}
+	}
+
+	messages := capture.Messages()
+	if len(messages) != 1 || messages[0].To != owner.Email || !strings.Contains(messages[0].Subject, "portfolio") {
+		t.Errorf("messages = %+v, want one reminder about portfolio to %s", messages, owner.Email)
+	}
+}
+
+func TestFailedExpiryReminderIsRetried(t *testing.T) {
+	n, capture, owner := newTestNotifier(t)
+	testutil.Link(t, n.DB, owner, "portfolio", 0)
+	now := time.Now().Add(time.Hour)
+
+	capture.Err = errors.New("smtp unavailable")
+	if sent, err := n.SendExpiryReminders(context.Background(), now); err != nil || sent != 0 {
+		t.Fatalf("failing run sent %d, %v; want 0", sent, err)
+	}
+
+	capture.Err = nil
+	if sent, err := n.SendExpiryReminders(context.Background(), now); err != nil || sent != 1 {
+		t.Fatalf("retry sent %d, %v; want 1", sent, err)
+	}
+	if len(capture.Messages()) != 1 {
+		t.Errorf("%d messages after the retry, want 1", len(capture.Messages()))
+	}
+}
+
+func TestWeeklyDigestIsSentOncePerInterval(t *testing.T) {
+	n, capture, owner := newTestNotifier(t)
+	testutil.Link(t, n.DB, owner, "portfolio", 0)
+	now := time.Now()
+
+	for run, want := range []int{1, 0} {
+		sent, err := n.SendWeeklyDigests(context.Background(), now)
+		if err != nil || sent != want {
+			t.Fatalf("run %d sent %d, %v; want %d", run+1, sent, err, want)
+		}
+	}
+
+	if sent, err := n.SendWeeklyDigests(context.Background(), now.Add(DigestInterval)); err != nil || sent != 1 {
+		t.Fatalf("next week sent %d, %v; want 1", sent, err)
+	}
+
+	messages := capture.Messages()
+	if len(messages) != 2 || messages[0].To != owner.Email || !strings.Contains(messages[0].Body, "portfolio") {
+		t.Errorf("messages = %+v, want two digests listing portfolio to %s", messages, owner.Email)
+	}
+}
+
+func TestFailedWeeklyDigestIsRetried(t *testing.T) {
+	n, capture, _ := newTestNotifier(t)
+	now := time.Now()
+
+	capture.Err = errors.New("smtp unavailable")
+	if sent, err := n.SendWeeklyDigests(context.Background(), now); err != nil || sent != 0 {
+		t.Fatalf("failing run sent %d, %v; want 0", sent, err)
+	}
+
+	capture.Err = nil
+	if sent, err := n.SendWeeklyDigests(context.Background(), now); err != nil || sent != 1 {
+		t.Fatalf("retry sent %d, %v; want 1", sent, err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package pathrules decides which repository paths a viewer link exposes.
+// Every viewer endpoint that lists, reads or searches repository content
+// must check paths through Rules so a link never leaks out of its scope.
+package pathrules
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Rules scope a link to an allowlist of paths and hide a denylist.
+// Patterns use doublestar globs ("services/payments/**", "**/.env").
+// An empty Allow list allows the whole repository; Deny always wins.
+type Rules struct {
+	Allow []string
+	Deny  []string
+}
+
+// To check that every pattern is a valid glob
+func Validate(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" || !doublestar.ValidatePattern(Clean(pattern)) {
+			return fmt.Errorf("invalid path pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// To normalise a repository path: no leading or trailing slash, "" for the root
+func Clean(p string) string {
+	p = path.Clean("/" + strings.TrimSpace(p))
+	return strings.TrimPrefix(p, "/")
+}
+
+// To check whether a file (or any non-directory entry) may be shown
+func (r Rules) AllowsFile(p string) bool {
+	p = Clean(p)
+	if p == "" || r.denied(p) {
+		return false
+	}
+
+	return len(r.Allow) == 0 || matchesSelfOrAncestor(r.Allow, p)
+}
+
+// To check whether a directory may be listed. Directories on the way to an
+// allowed path are visible so viewers can navigate down to it.
+func (r Rules) AllowsDir(p string) bool {
+	p = Clean(p)
+	if p == "" {
+		return true
+	}
+	if r.denied(p) {
+		return false
+	}
+	if len(r.Allow) == 0 || matchesSelfOrAncestor(r.Allow, p) {
+		return true
+	}
+
+	for _, pattern := range r.Allow {
+		if leadsTo(p, Clean(pattern)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Rules) denied(p string) bool {
+	return matchesSelfOrAncestor(r.Deny, p)
+}
+
+// To match p or any of its parent directories against the patterns
+func matchesSelfOrAncestor(patterns []string, p string) bool {
+	for candidate := p; candidate != "." && candidate != ""; candidate = path.Dir(candidate) {
+		for _, pattern := range patterns {
+			if ok, _ := doublestar.Match(Clean(pattern), candidate); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// To check whether dir is a directory that pattern could match something beneath
+func leadsTo(dir, pattern string) bool {
+	dirSegments := strings.Split(dir, "/")
+	patternSegments := strings.Split(pattern, "/")
+
+	for i, segment := range dirSegments {
+		if i >= len(patternSegments) {
+			return false
+		}
+		if patternSegments[i] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(patternSegments[i], segment); !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package pathrules
+
+import "testing"
+
+func TestRules(t *testing.T) {
+	scoped := Rules{Allow: []string{"services/payments/**", "README.md"}, Deny: []string{"**/.env", "services/payments/secrets"}}
+
+	tests := []struct {
+		name  string
+		rules Rules
+		path  string
+		dir   bool
+		want  bool
+	}{
+		{"no rules allow everything", Rules{}, "src/main.go", false, true},
+		{"root is never a file", Rules{}, "", false, false},
+		{"root directory is always listed", scoped, "", true, true},
+		{"allowed file", scoped, "services/payments/api.go", false, true},
+		{"allowed by exact path", scoped, "README.md", false, true},
+		{"outside the allowlist", scoped, "services/billing/api.go", false, false},
+		{"deny wins over allow", scoped, "services/payments/.env", false, false},
+		{"deny glob anywhere", Rules{Deny: []string{"**/.env"}}, "deploy/prod/.env", false, false},
+		{"denied directory hides its files", scoped, "services/payments/secrets/key.pem", false, false},
+		{"denied directory is not listed", scoped, "services/payments/secrets", true, false},
+		{"parent of an allowed path is listed", scoped, "services", true, true},
+		{"sibling of an allowed path is not listed", scoped, "services/billing", true, false},
+		{"allowed directory is listed", scoped, "services/payments/internal", true, true},
+		{"leading and trailing slashes are ignored", scoped, "/services/payments/api.go/", false, true},
+		{"dot segments cannot escape the scope", scoped, "services/payments/../billing/api.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rules.AllowsFile(tt.path)
+			if tt.dir {
+				got = tt.rules.AllowsDir(tt.path)
+			}
+			if got != tt.want {
+				t.Errorf("allows %q (dir %v) = %v, want %v", tt.path, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		valid    bool
+	}{
+		{[]string{"src/**", "**/*.go"}, true},
+		{[]string{"docs/[a-z]*.md"}, true},
+		{[]string{"  "}, false},
+		{[]string{"src/[unclosed"}, false},
+	}
+
+	for _, tt := range tests {
+		if err := Validate(tt.patterns); (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", tt.patterns, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package privyignore reads the .privyignore file an owner commits to a
+// repository. It uses gitignore syntax, and matching paths are hidden from
+// every viewer link of that repository.
+package privyignore
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/github"
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+const FileName = ".privyignore"
+
+// Matcher reports whether a path is hidden by a .privyignore file.
+// A nil Matcher hides nothing.
+type Matcher struct {
+	gitignore *ignore.GitIgnore
+}
+
+// To compile the contents of a .privyignore file
+func Parse(content []byte) *Matcher {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	return &Matcher{gitignore: ignore.CompileIgnoreLines(lines...)}
+}
+
+// To check whether p (or a directory containing it) is ignored.
+// The .privyignore file itself is always hidden, since it names what is hidden.
+// As in git, a negated pattern cannot show a file again once a directory
+// above it is hidden; unlike git, "dir/**" hides dir itself too.
+func (m *Matcher) Ignored(p string, isDir bool) bool {
+	p = strings.Trim(p, "/")
+	if p == FileName {
+		return true
+	}
+	if m == nil || m.gitignore == nil || p == "" {
+		return false
+	}
+
+	if isDir && m.gitignore.MatchesPath(p+"/") {
+		return true
+	}
+
+	for candidate := p; candidate != "." && candidate != ""; candidate = path.Dir(candidate) {
+		if m.gitignore.MatchesPath(candidate) {
+			return true
+		}
+		// To let directory-only patterns ("build/") hide everything beneath them
+		if candidate != p && m.gitignore.MatchesPath(candidate+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Cache keeps the compiled .privyignore of each repository and ref for a
+// short time, so browsing a repo does not refetch it on every request.
+type Cache struct {
+	ttl     time.Duration
+	clock   func() time.Time
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	matcher   *Matcher
+	fetchedAt time.Time
+}
+
+// To bound memory; stale entries are dropped once the cache grows past this
+const maxEntries = 512
+
+// To create a cache whose entries go stale ttl after they were fetched, by clock
+func NewCache(ttl time.Duration, clock func() time.Time) *Cache {
+	return &Cache{ttl: ttl, clock: clock, entries: map[string]cacheEntry{}}
+}
+
+// To get the matcher for a repository at a ref, fetching it from GitHub when
+// missing or stale. A repository without a .privyignore gets an empty matcher.
+func (c *Cache) Get(ctx context.Context, client github.Client, token, owner, repo, ref string) (*Matcher, error) {
+	key := owner + "/" + repo + "@" + ref
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && c.clock().Sub(entry.fetchedAt) < c.ttl {
+		return entry.matcher, nil
+	}
+
+	content, err := client.GetFileRaw(ctx, token, owner, repo, FileName, ref)
+	if err != nil && !github.IsNotFound(err) {
+		return nil, err
+	}
+
+	matcher := Parse(content)
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{matcher: matcher, fetchedAt: c.clock()}
+	if len(c.entries) > maxEntries {
+		c.evictStale()
+	}
+	c.mu.Unlock()
+
+	return matcher, nil
+}
+
+// To drop expired entries; the caller holds c.mu
+func (c *Cache) evictStale() {
+	now := c.clock()
+	for key, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package privyignore
+
+import "testing"
+
+func TestIgnored(t *testing.T) {
+	matcher := Parse([]byte("# Hidden from viewers\r\n" +
+		"build/\n" +
+		"*.log\n" +
+		"!keep.log\n" +
+		"/notes.md\n" +
+		"secrets/**\n" +
+		"!secrets/README.md\n" +
+		"!build/index.html\n"))
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"directory-only pattern hides the directory", "build", true, true},
+		{"directory-only pattern hides files beneath it", "build/out/app.js", false, true},
+		{"directory-only pattern hides nested directories", "web/build", true, true},
+		{"directory-only pattern skips a file of that name", "build", false, false},
+		{"glob hides matching files", "logs/server.log", false, true},
+		{"negated pattern shows the file again", "keep.log", false, false},
+		{"anchored pattern hides the root file", "notes.md", false, true},
+		{"anchored pattern keeps nested files", "docs/notes.md", false, false},
+		{"double star hides everything beneath", "secrets/prod/key.pem", false, true},
+		{"negation cannot re-include under a hidden directory", "build/index.html", false, true},
+		{"negation cannot re-include under a double star", "secrets/README.md", false, true},
+		{"unmatched path", "src/main.go", false, false},
+		{"the .privyignore file itself", ".privyignore", false, true},
+		{"surrounding slashes are ignored", "/build/app.js/", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matcher.Ignored(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilMatcherHidesOnlyItself(t *testing.T) {
+	var matcher *Matcher
+
+	if matcher.Ignored("src/main.go", false) {
+		t.Error("nil matcher hid a file")
+	}
+	if !matcher.Ignored(FileName, false) {
+		t.Error("nil matcher showed the .privyignore file")
+	}
+}
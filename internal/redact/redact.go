@@ -0,0 +1,59 @@
+// Package redact masks likely secrets in file contents before they are
+// served to viewers. A Redactor runs an ordered set of Rules; the default
+// set covers cloud keys, GitHub tokens, private keys, dotenv values and
+// high-entropy strings, and callers can supply their own.
+package redact
+
+import (
+	"bytes"
+)
+
+// Mask replaces every redacted value
+const Mask = "[REDACTED]"
+
+// Rule finds and masks one kind of secret. Apply returns the new content and
+// the number of values it masked.
+type Rule interface {
+	Name() string
+	Apply(path string, content []byte) ([]byte, int)
+}
+
+type Redactor struct {
+	Rules []Rule
+}
+
+// To create a redactor with the given rules, run in order
+func New(rules ...Rule) *Redactor {
+	return &Redactor{Rules: rules}
+}
+
+// To create a redactor with the built-in rules
+func Default() *Redactor {
+	return New(DefaultRules()...)
+}
+
+// To mask secrets in content. Binary files are returned unchanged.
+func (r *Redactor) Redact(path string, content []byte) ([]byte, int) {
+	if isBinary(content) {
+		return content, 0
+	}
+
+	total := 0
+	for _, rule := range r.Rules {
+		var count int
+		content, count = rule.Apply(path, content)
+		total += count
+	}
+
+	return content, total
+}
+
+// To guess whether content is binary, the way git does: a NUL byte in the first 8KB
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+
+	return bytes.IndexByte(head, 0) >= 0
+}
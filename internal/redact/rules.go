@@ -0,0 +1,168 @@
+package redact
+
+import (
+	"bytes"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// To get the built-in rules. Specific patterns run first so their matches
+// are already masked when the generic high-entropy rule runs.
+func DefaultRules() []Rule {
+	return []Rule{
+		PrivateKeyRule(),
+		PatternRule("aws-access-key-id", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		PatternRule("aws-secret-access-key", `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`),
+		PatternRule("github-token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		DotenvRule(),
+		HighEntropyRule(20, 4.0),
+	}
+}
+
+type patternRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// To create a rule masking every match of a regular expression. If the
+// expression has a capture group, only the first group is masked.
+func PatternRule(name, expr string) Rule {
+	return &patternRule{name: name, pattern: regexp.MustCompile(expr)}
+}
+
+func (r *patternRule) Name() string { return r.name }
+
+func (r *patternRule) Apply(_ string, content []byte) ([]byte, int) {
+	return replaceMatches(content, r.pattern)
+}
+
+// To mask each match (or its first capture group) of pattern in content
+func replaceMatches(content []byte, pattern *regexp.Regexp) ([]byte, int) {
+	matches := pattern.FindAllSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content, 0
+	}
+
+	var out bytes.Buffer
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if len(match) >= 4 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+
+		out.Write(content[last:start])
+		out.WriteString(Mask)
+		last = end
+	}
+	out.Write(content[last:])
+
+	return out.Bytes(), len(matches)
+}
+
+// To mask whole PEM private key blocks, including the BEGIN/END lines
+func PrivateKeyRule() Rule {
+	return PatternRule("private-key", `(?s)-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----.*?-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----`)
+}
+
+type dotenvRule struct{}
+
+// To mask the value of every KEY=value line in dotenv files (.env, .env.local, app.env, ...)
+func DotenvRule() Rule {
+	return dotenvRule{}
+}
+
+var dotenvLine = regexp.MustCompile(`(?m)^\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_.]*\s*=\s*(.+?)\s*$`)
+
+func (dotenvRule) Name() string { return "dotenv" }
+
+func (dotenvRule) Apply(filePath string, content []byte) ([]byte, int) {
+	name := path.Base(filePath)
+	if !(name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env")) {
+		return content, 0
+	}
+
+	// To leave documented placeholders in example files alone
+	if strings.HasSuffix(name, ".example") || strings.HasSuffix(name, ".sample") || strings.HasSuffix(name, ".template") {
+		return content, 0
+	}
+
+	return replaceMatches(content, dotenvLine)
+}
+
+type highEntropyRule struct {
+	minLength int
+	threshold float64
+}
+
+// To mask long tokens whose Shannon entropy (bits per character) is above
+// threshold, which catches most API keys and passwords that no specific rule knows
+func HighEntropyRule(minLength int, threshold float64) Rule {
+	return &highEntropyRule{minLength: minLength, threshold: threshold}
+}
+
+var candidateToken = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{8,}`)
+
+func (r *highEntropyRule) Name() string { return "high-entropy" }
+
+// Lockfiles are full of integrity hashes that are not secrets
+var lockfiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"Gemfile.lock":      true,
+	"composer.lock":     true,
+}
+
+func (r *highEntropyRule) Apply(filePath string, content []byte) ([]byte, int) {
+	if lockfiles[path.Base(filePath)] {
+		return content, 0
+	}
+
+	count := 0
+	out := candidateToken.ReplaceAllFunc(content, func(token []byte) []byte {
+		if len(token) < r.minLength || !mixedCharacters(token) || entropy(token) < r.threshold {
+			return token
+		}
+
+		count++
+		return []byte(Mask)
+	})
+
+	return out, count
+}
+
+// To skip identifiers, paths and hex-free words: a secret usually mixes digits and letters
+func mixedCharacters(token []byte) bool {
+	var hasDigit, hasLetter bool
+	for _, c := range token {
+		switch {
+		case c >= '0' && c <= '9':
+			hasDigit = true
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			hasLetter = true
+		}
+	}
+
+	return hasDigit && hasLetter
+}
+
+func entropy(token []byte) float64 {
+	counts := map[byte]int{}
+	for _, c := range token {
+		counts[c]++
+	}
+
+	var bits float64
+	for _, n := range counts {
+		p := float64(n) / float64(len(token))
+		bits -= p * math.Log2(p)
+	}
+
+	return bits
+}
@@ -7,23 +7,41 @@ import (
 	"github.com/greatdaveo/privycode-server/internal/middleware"
 )
 
-func APIRoutes(mux *http.ServeMux) {
+// To register every API route on mux, served by app
+func APIRoutes(mux *http.ServeMux, app *handlers.App) {
+	auth := middleware.AuthMiddleware(app.DB, app.Clock)
+
 	mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Welcome to PrivyCode 👋"))
 	})
 
-	mux.HandleFunc("/github/login", handlers.GitHubLoginHandler)
-	mux.HandleFunc("/dashboard", middleware.AuthMiddleware(handlers.DashboardHandler))
-	mux.HandleFunc("/github/callback", handlers.GitHubCallbackHandler)
-	mux.HandleFunc("/me", middleware.AuthMiddleware(handlers.MeHandler))
+	mux.HandleFunc("/github/login", app.GitHubLoginHandler)
+	mux.HandleFunc("/dashboard", auth(app.DashboardHandler))
+	mux.HandleFunc("/github/callback", app.GitHubCallbackHandler)
+	mux.HandleFunc("POST /auth/exchange", app.ExchangeLoginCodeHandler)
+	mux.HandleFunc("/me", auth(app.MeHandler))
+	mux.HandleFunc("/logout", auth(app.LogoutHandler))
+	mux.HandleFunc("/logout-all", auth(app.LogoutAllHandler))
+	mux.HandleFunc("GET /notifications", auth(app.GetNotificationsHandler))
+	mux.HandleFunc("PUT /notifications", auth(app.UpdateNotificationsHandler))
+
+	mux.HandleFunc("/generate-viewer-link", auth(app.GenerateViewerLinkHandler))
+	mux.HandleFunc("/update-link/", auth(app.UpdateViewerLinkHandler))
+	mux.HandleFunc("/delete-link/", auth(app.DeleteViewerLinkHandler))
+	mux.HandleFunc("GET /links/{id}/analytics", auth(app.LinkAnalyticsHandler))
 
-	mux.HandleFunc("/generate-viewer-link", middleware.AuthMiddleware(handlers.GenerateViewerLinkHandler))
-	mux.HandleFunc("/update-link/", middleware.AuthMiddleware(handlers.UpdateViewerLinkHandler))
-	mux.HandleFunc("/delete-link/", middleware.AuthMiddleware(handlers.DeleteViewerLinkHandler))
+	mux.HandleFunc("GET /webhook", auth(app.GetWebhookHandler))
+	mux.HandleFunc("PUT /webhook", auth(app.SaveWebhookHandler))
+	mux.HandleFunc("DELETE /webhook", auth(app.DeleteWebhookHandler))
+	mux.HandleFunc("GET /webhook/deliveries", auth(app.WebhookDeliveriesHandler))
+	mux.HandleFunc("POST /webhook/test", auth(app.TestWebhookHandler))
 
-	mux.HandleFunc("/view/", handlers.ViewerAccessHandler)
-	mux.HandleFunc("/view-files/", handlers.ViewFileHandler)
-	mux.HandleFunc("/view-folder/", handlers.ViewerFolderHandler)
+	mux.HandleFunc("/view/", app.ViewerAccessHandler)
+	mux.HandleFunc("/view-files/", app.ViewFileHandler)
+	mux.HandleFunc("/view-folder/", app.ViewerFolderHandler)
 
-	mux.HandleFunc("/view-info/", handlers.ViewUserInfoHandler)
+	mux.HandleFunc("/view-info/", app.ViewUserInfoHandler)
+	mux.HandleFunc("/view-unlock/", app.ViewerUnlockHandler)
+	mux.HandleFunc("/view-request-access/", app.ViewerRequestAccessHandler)
+	mux.HandleFunc("/view-verify/", app.ViewerVerifyEmailHandler)
 }
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/config"
+	"github.com/greatdaveo/privycode-server/internal/github"
+	"github.com/greatdaveo/privycode-server/internal/handlers"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/testutil"
+)
+
+// To build the full API on a test App: in-memory SQLite, fake GitHub and a
+// clock stopped at now
+func newTestAPI(t *testing.T, now time.Time) (*handlers.App, *http.ServeMux) {
+	t.Helper()
+
+	db := testutil.DB(t)
+	app := handlers.NewApp(db, config.Defaults())
+	app.GitHub = github.NewFakeClient()
+	app.Clock = func() time.Time { return now }
+
+	mux := http.NewServeMux()
+	APIRoutes(mux, app)
+
+	return app, mux
+}
+
+func TestAPIRoutesUseAppClock(t *testing.T) {
+	now := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	app, mux := newTestAPI(t, now)
+
+	user := testutil.User(t, app.DB, "alice")
+
+	// To issue a session that is live on the app's clock but long expired on
+	// the wall clock, so it only works if the auth middleware uses app.Clock
+	authorization := testutil.Session(t, app.DB, user, now.Add(time.Hour))
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", authorization)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"github_username":"alice"`) {
+		t.Fatalf("GET /me = %d %s, want 200 for alice", rec.Code, rec.Body)
+	}
+
+	var used models.Session
+	if err := app.DB.Where("user_id = ?", user.ID).First(&used).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !used.LastUsedAt.Equal(now) {
+		t.Errorf("last_used_at = %v, want the app clock's %v", used.LastUsedAt, now)
+	}
+}
+
+func TestAPIRoutesRequireSession(t *testing.T) {
+	_, mux := newTestAPI(t, time.Now())
+
+	for _, path := range []string{"/me", "/dashboard", "/webhook"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without a session = %d, want 401", path, rec.Code)
+		}
+	}
+}
+
+func TestAPIResponsesLeaveOutSecrets(t *testing.T) {
+	app, mux := newTestAPI(t, time.Now())
+	fake := github.NewFakeClient()
+	fake.AddRepo("alice", "portfolio", map[string]string{"README.md": "hello"})
+	app.GitHub = fake
+
+	user := testutil.User(t, app.DB, "alice")
+	user.GitHubToken = "SENTINEL-github-token"
+	if err := app.DB.Save(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	authorization := testutil.Session(t, app.DB, user, time.Now().Add(time.Hour))
+
+	link := testutil.Link(t, app.DB, user, "portfolio", 0)
+	public := testutil.Link(t, app.DB, user, "portfolio", 0)
+	if err := app.DB.Model(link).Update("password_hash", "SENTINEL-password-hash").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	webhook := models.Webhook{UserID: user.ID, URL: "https://hooks.example.com/privycode", Active: true, Secret: "SENTINEL-webhook-secret"}
+	if err := app.DB.Create(&webhook).Error; err != nil {
+		t.Fatal(err)
+	}
+	delivery := models.WebhookDelivery{WebhookID: webhook.ID, UUID: "delivery-id", Event: "link.created", Payload: `{}`, Status: models.DeliveryPending}
+	if err := app.DB.Create(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// To open the public link once, so there are viewer sessions and view events to report
+	viewer := httptest.NewRecorder()
+	mux.ServeHTTP(viewer, httptest.NewRequest(http.MethodGet, "/view/"+public.Token, nil))
+	if viewer.Code != http.StatusOK {
+		t.Fatalf("GET /view = %d (%s)", viewer.Code, viewer.Body)
+	}
+
+	// To collect every stored secret and hash, as the database holds them
+	var stored struct {
+		TokenCiphertext, WebhookCiphertext, SessionHash, ViewerSessionHash, IPHash string
+	}
+	app.DB.Model(&models.User{}).Where("id = ?", user.ID).Select("git_hub_token_ciphertext").Scan(&stored.TokenCiphertext)
+	app.DB.Model(&models.Webhook{}).Where("id = ?", webhook.ID).Select("secret_ciphertext").Scan(&stored.WebhookCiphertext)
+	app.DB.Model(&models.Session{}).Where("user_id = ?", user.ID).Select("token_hash").Scan(&stored.SessionHash)
+	app.DB.Model(&models.ViewerSession{}).Where("viewer_link_id = ?", public.ID).Select("token_hash").Scan(&stored.ViewerSessionHash)
+	app.DB.Model(&models.ViewEvent{}).Where("viewer_link_id = ?", public.ID).Select("ip_hash").Scan(&stored.IPHash)
+
+	secrets := map[string]string{
+		"GitHub token":              "SENTINEL-github-token",
+		"GitHub token ciphertext":   stored.TokenCiphertext,
+		"link password hash":        "SENTINEL-password-hash",
+		"webhook secret":            "SENTINEL-webhook-secret",
+		"webhook secret ciphertext": stored.WebhookCiphertext,
+		"session token hash":        stored.SessionHash,
+		"viewer session hash":       stored.ViewerSessionHash,
+		"IP hash":                   stored.IPHash,
+	}
+	for name, value := range secrets {
+		if value == "" {
+			t.Fatalf("no stored %s to look for", name)
+		}
+	}
+
+	for _, path := range []string{
+		"/me",
+		"/dashboard",
+		"/notifications",
+		"/links/" + link.PublicID + "/analytics",
+		"/links/" + public.PublicID + "/analytics",
+		"/webhook",
+		"/webhook/deliveries",
+		"/view-info/" + public.Token,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200 (%s)", path, rec.Code, rec.Body)
+			continue
+		}
+		for name, value := range secrets {
+			if strings.Contains(rec.Body.String(), value) {
+				t.Errorf("GET %s leaks the %s: %s", path, name, rec.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Envelope format version, stored as the first byte of every ciphertext
+const envelopeV1 byte = 1
+
+const (
+	keySize   = 32 // AES-256
+	nonceSize = 12 // standard AES-GCM nonce
+)
+
+var (
+	ErrNoKeyring  = errors.New("token encryption keys are not configured")
+	ErrUnknownKey = errors.New("unknown token encryption key ID")
+	ErrCiphertext = errors.New("malformed token ciphertext")
+)
+
+// Keyring holds the key-encryption keys (KEKs) used to protect stored tokens.
+// Every value is encrypted with a fresh data key, and that data key is wrapped
+// with the active KEK, so rotating keys only requires re-wrapping.
+type Keyring struct {
+	keys     map[string][]byte
+	activeID string
+}
+
+// To parse a keyring from "id:base64key,id2:base64key" and the ID of the key used for new writes
+func ParseKeyring(spec, activeID string) (*Keyring, error) {
+	keys := map[string][]byte{}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key entry %q, expected id:base64key", entry)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+
+		keys[id] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNoKeyring
+	}
+
+	if activeID == "" && len(keys) == 1 {
+		for id := range keys {
+			activeID = id
+		}
+	}
+
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeID)
+	}
+
+	return &Keyring{keys: keys, activeID: activeID}, nil
+}
+
+// To return the ID of the key used for new encryptions
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// To encrypt plaintext under the active key, returning the encoded ciphertext and its key ID
+func (k *Keyring) Encrypt(plaintext string) (string, string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", "", err
+	}
+
+	// To encrypt the value with the data key, then wrap the data key with the KEK
+	dataNonce, sealed, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	wrapNonce, wrappedKey, err := seal(k.keys[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return "", "", err
+	}
+
+	blob := []byte{envelopeV1}
+	blob = append(blob, wrapNonce...)
+	blob = append(blob, wrappedKey...)
+	blob = append(blob, dataNonce...)
+	blob = append(blob, sealed...)
+
+	return base64.StdEncoding.EncodeToString(blob), k.activeID, nil
+}
+
+// To decrypt a ciphertext produced by Encrypt with the given key ID
+func (k *Keyring) Decrypt(ciphertext, keyID string) (string, error) {
+	kek, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrCiphertext
+	}
+
+	// version | wrap nonce | wrapped data key (+GCM tag) | data nonce | sealed value
+	wrappedLen := keySize + 16
+	if len(blob) < 1+nonceSize+wrappedLen+nonceSize || blob[0] != envelopeV1 {
+		return "", ErrCiphertext
+	}
+	blob = blob[1:]
+
+	wrapNonce, blob := blob[:nonceSize], blob[nonceSize:]
+	wrappedKey, blob := blob[:wrappedLen], blob[wrappedLen:]
+	dataNonce, sealed := blob[:nonceSize], blob[nonceSize:]
+
+	dataKey, err := open(kek, wrapNonce, wrappedKey, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataKey, dataNonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func seal(key, plaintext, additionalData []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func open(key, nonce, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCiphertext, err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
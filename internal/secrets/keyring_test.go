@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// To build a keyring entry whose 32-byte key is fill repeated
+func keyEntry(id string, fill byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(fill), keySize)))
+}
+
+func TestParseKeyring(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		activeID string
+		wantErr  bool
+		active   string
+	}{
+		{"single key is active by default", keyEntry("k1", 'a'), "", false, "k1"},
+		{"active key picked explicitly", keyEntry("k1", 'a') + ", " + keyEntry("k2", 'b'), "k2", false, "k2"},
+		{"several keys need an active ID", keyEntry("k1", 'a') + "," + keyEntry("k2", 'b'), "", true, ""},
+		{"active ID must be in the keyring", keyEntry("k1", 'a'), "k9", true, ""},
+		{"empty spec", " , ", "", true, ""},
+		{"missing ID", ":" + base64.StdEncoding.EncodeToString(make([]byte, keySize)), "", true, ""},
+		{"not base64", "k1:not-base64!", "", true, ""},
+		{"wrong key size", "k1:" + base64.StdEncoding.EncodeToString(make([]byte, 16)), "", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring(tt.spec, tt.activeID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseKeyring(%q, %q) succeeded, want an error", tt.spec, tt.activeID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keyring.ActiveKeyID() != tt.active {
+				t.Errorf("active key = %q, want %q", keyring.ActiveKeyID(), tt.active)
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, err := ParseKeyring(keyEntry("k1", 'a'), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, keyID, err := old.Encrypt("gho_token")
+	if err != nil || keyID != "k1" {
+		t.Fatalf("Encrypt = %q, %v; want key k1", keyID, err)
+	}
+
+	// To rotate: k2 becomes active and k1 stays to decrypt existing values
+	rotated, err := ParseKeyring(keyEntry("k1", 'a')+","+keyEntry("k2", 'b'), "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plaintext, err := rotated.Decrypt(ciphertext, keyID); err != nil || plaintext != "gho_token" {
+		t.Fatalf("old value after rotation = %q, %v", plaintext, err)
+	}
+
+	reencrypted, newKeyID, err := rotated.Encrypt("gho_token")
+	if err != nil || newKeyID != "k2" {
+		t.Fatalf("re-encrypt = %q, %v; want key k2", newKeyID, err)
+	}
+
+	// To retire k1 once everything is re-encrypted under k2
+	retired, err := ParseKeyring(keyEntry("k2", 'b'), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := retired.Decrypt(reencrypted, newKeyID); err != nil || plaintext != "gho_token" {
+		t.Errorf("re-encrypted value after retiring k1 = %q, %v", plaintext, err)
+	}
+	if _, err := retired.Decrypt(ciphertext, keyID); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("value under the retired key = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	keyring, err := ParseKeyring(keyEntry("k1", 'a')+","+keyEntry("k2", 'b'), "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, _, err := keyring.Encrypt("gho_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob, _ := base64.StdEncoding.DecodeString(ciphertext)
+	blob[len(blob)-1] ^= 1
+	tampered := base64.StdEncoding.EncodeToString(blob)
+
+	tests := []struct {
+		name       string
+		ciphertext string
+		keyID      string
+		want       error
+	}{
+		{"unknown key ID", ciphertext, "k9", ErrUnknownKey},
+		{"other key's ID", ciphertext, "k2", ErrCiphertext},
+		{"tampered value", tampered, "k1", ErrCiphertext},
+		{"not base64", "%%%", "k1", ErrCiphertext},
+		{"truncated", ciphertext[:20], "k1", ErrCiphertext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keyring.Decrypt(tt.ciphertext, tt.keyID); !errors.Is(err, tt.want) {
+				t.Errorf("Decrypt = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// GormLinkStore is the LinkStore on a GORM database, PostgreSQL or SQLite
+type GormLinkStore struct {
+	DB *gorm.DB
+}
+
+func NewGormLinkStore(db *gorm.DB) *GormLinkStore {
+	return &GormLinkStore{DB: db}
+}
+
+func (s *GormLinkStore) ByToken(token string) (*models.ViewerLink, error) {
+	var link models.ViewerLink
+	if err := s.DB.Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, notFound(err)
+	}
+
+	return &link, nil
+}
+
+func (s *GormLinkStore) ByTokenWithDeleted(token string) (*models.ViewerLink, error) {
+	var link models.ViewerLink
+	if err := s.DB.Unscoped().Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, notFound(err)
+	}
+
+	return &link, nil
+}
+
+func (s *GormLinkStore) ByPublicID(userID uint, publicID string) (*models.ViewerLink, error) {
+	var link models.ViewerLink
+	if err := s.DB.Where("public_id = ? AND user_id = ?", publicID, userID).First(&link).Error; err != nil {
+		return nil, notFound(err)
+	}
+
+	return &link, nil
+}
+
+func (s *GormLinkStore) ForUser(userID uint) ([]models.ViewerLink, error) {
+	var links []models.ViewerLink
+	if err := s.DB.Where("user_id = ?", userID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (s *GormLinkStore) Create(link *models.ViewerLink) error {
+	return s.DB.Create(link).Error
+}
+
+// The columns Update writes; everything else is changed by viewers or jobs
+var linkOwnerColumns = []string{
+	"updated_at", "expires_at", "max_views", "allow_paths", "deny_paths",
+	"disable_redaction", "password_hash", "access_mode", "allowed_emails",
+}
+
+func (s *GormLinkStore) Update(link *models.ViewerLink) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.ViewerLink
+		if err := tx.Select("expires_at").First(&current, link.ID).Error; err != nil {
+			return notFound(err)
+		}
+
+		columns := linkOwnerColumns
+		if !current.ExpiresAt.Equal(link.ExpiresAt) {
+			link.ExpiryNotifiedAt = nil
+			link.ExpiryReminderSentAt = nil
+			columns = append(columns[:len(columns):len(columns)], "expiry_notified_at", "expiry_reminder_sent_at")
+		}
+
+		return tx.Model(link).Select(columns).Updates(link).Error
+	})
+}
+
+func (s *GormLinkStore) Delete(link *models.ViewerLink) error {
+	return s.DB.Delete(link).Error
+}
+
+func (s *GormLinkStore) AddView(link *models.ViewerLink, session *models.ViewerSession) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ViewerLink{}).
+			Where("id = ? AND (max_views <= 0 OR view_count < max_views)", link.ID).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrViewLimitReached
+		}
+
+		if err := tx.Model(&models.ViewerLink{}).Where("id = ?", link.ID).Select("view_count").Scan(&link.ViewCount).Error; err != nil {
+			return err
+		}
+
+		session.ViewerLinkID = link.ID
+		return tx.Create(session).Error
+	})
+}
+
+func (s *GormLinkStore) RecordFailedUnlock(link *models.ViewerLink, maxFailed int, lockedUntil time.Time) error {
+	err := s.DB.Model(&models.ViewerLink{}).Where("id = ?", link.ID).
+		UpdateColumn("failed_unlocks", gorm.Expr("failed_unlocks + 1")).Error
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Model(&models.ViewerLink{}).
+		Where("id = ? AND failed_unlocks >= ?", link.ID, maxFailed).
+		UpdateColumns(map[string]any{
+			"failed_unlocks": 0,
+			"locked_until":   lockedUntil,
+		}).Error
+}
+
+func (s *GormLinkStore) ResetFailedUnlocks(link *models.ViewerLink) error {
+	return s.DB.Model(link).UpdateColumns(map[string]any{"failed_unlocks": 0, "locked_until": nil}).Error
+}
+
+func (s *GormLinkStore) MarkExpiryNotified(link *models.ViewerLink, at time.Time) (bool, error) {
+	result := s.DB.Model(&models.ViewerLink{}).
+		Where("id = ? AND expiry_notified_at IS NULL", link.ID).
+		UpdateColumn("expiry_notified_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// GormUserStore is the UserStore on a GORM database, PostgreSQL or SQLite
+type GormUserStore struct {
+	DB *gorm.DB
+}
+
+func NewGormUserStore(db *gorm.DB) *GormUserStore {
+	return &GormUserStore{DB: db}
+}
+
+func (s *GormUserStore) ByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := s.DB.First(&user, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+
+	return &user, nil
+}
+
+func (s *GormUserStore) ByGitHubUsername(username string) (*models.User, error) {
+	var user models.User
+	if err := s.DB.Where("git_hub_username = ?", username).First(&user).Error; err != nil {
+		return nil, notFound(err)
+	}
+
+	return &user, nil
+}
+
+func (s *GormUserStore) Create(user *models.User) error {
+	return s.DB.Create(user).Error
+}
+
+func (s *GormUserStore) Save(user *models.User) error {
+	return s.DB.Save(user).Error
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,164 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/internal/testutil"
+)
+
+// To open a migrated in-memory SQLite database with one user and one link
+func newTestLinkStore(t *testing.T, maxViews int) (*store.GormLinkStore, *models.ViewerLink) {
+	t.Helper()
+
+	db := testutil.DB(t)
+	user := testutil.User(t, db, "owner")
+	return store.NewGormLinkStore(db), testutil.Link(t, db, user, "portfolio", maxViews)
+}
+
+func newSession(hash string) *models.ViewerSession {
+	return &models.ViewerSession{TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour), LastSeenAt: time.Now()}
+}
+
+func TestAddViewStopsAtLimit(t *testing.T) {
+	links, link := newTestLinkStore(t, 2)
+
+	for i, hash := range []string{"first", "second"} {
+		if err := links.AddView(link, newSession(hash)); err != nil {
+			t.Fatalf("view %d: %v", i+1, err)
+		}
+		if link.ViewCount != i+1 {
+			t.Errorf("after view %d ViewCount = %d", i+1, link.ViewCount)
+		}
+	}
+
+	if err := links.AddView(link, newSession("third")); !errors.Is(err, store.ErrViewLimitReached) {
+		t.Fatalf("view past the limit = %v, want ErrViewLimitReached", err)
+	}
+
+	var sessions int64
+	links.DB.Model(&models.ViewerSession{}).Where("viewer_link_id = ?", link.ID).Count(&sessions)
+	if sessions != 2 {
+		t.Errorf("%d viewer sessions, want 2: a refused view must not create one", sessions)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	links, link := newTestLinkStore(t, 5)
+
+	link.MaxViews = 50
+	link.DenyPaths = []string{"**/.env"}
+	if err := links.Update(link); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := links.ByToken(link.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxViews != 50 || len(got.DenyPaths) != 1 || got.DenyPaths[0] != "**/.env" {
+		t.Errorf("after Update got max views %d, deny paths %v", got.MaxViews, got.DenyPaths)
+	}
+}
+
+func TestRecordFailedUnlockLocksAtLimit(t *testing.T) {
+	links, link := newTestLinkStore(t, 0)
+	lockedUntil := time.Now().Add(15 * time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if err := links.RecordFailedUnlock(link, 3, lockedUntil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := links.ByToken(link.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LockedUntil == nil || got.FailedUnlocks != 0 {
+		t.Fatalf("after 3 failures got locked until %v, failed unlocks %d", got.LockedUntil, got.FailedUnlocks)
+	}
+
+	if err := links.ResetFailedUnlocks(got); err != nil {
+		t.Fatal(err)
+	}
+	got, err = links.ByToken(link.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LockedUntil != nil {
+		t.Errorf("after reset got locked until %v", got.LockedUntil)
+	}
+}
+
+func TestMarkExpiryNotifiedOnce(t *testing.T) {
+	links, link := newTestLinkStore(t, 0)
+
+	marked, err := links.MarkExpiryNotified(link, time.Now())
+	if err != nil || !marked {
+		t.Fatalf("first mark = %v, %v; want true", marked, err)
+	}
+
+	marked, err = links.MarkExpiryNotified(link, time.Now())
+	if err != nil || marked {
+		t.Errorf("second mark = %v, %v; want false", marked, err)
+	}
+}
+
+func TestUpdateKeepsViewerCounters(t *testing.T) {
+	links, link := newTestLinkStore(t, 1)
+
+	// To edit a copy loaded before the view, as the update handler would
+	stale := *link
+	if err := links.AddView(link, newSession("viewer")); err != nil {
+		t.Fatal(err)
+	}
+
+	stale.DisableRedaction = true
+	if err := links.Update(&stale); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := links.ByToken(link.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ViewCount != 1 {
+		t.Errorf("view count = %d after an owner edit, want 1", got.ViewCount)
+	}
+	if !got.DisableRedaction {
+		t.Error("owner edit was not saved")
+	}
+	if err := links.AddView(got, newSession("second")); !errors.Is(err, store.ErrViewLimitReached) {
+		t.Errorf("view after the edit = %v, want ErrViewLimitReached", err)
+	}
+}
+
+func TestUpdateClearsExpiryNoticesWhenExtended(t *testing.T) {
+	links, link := newTestLinkStore(t, 0)
+	if _, err := links.MarkExpiryNotified(link, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// To leave the notice alone when the expiry does not move
+	link.MaxViews = 3
+	if err := links.Update(link); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := links.ByToken(link.Token)
+	if got.ExpiryNotifiedAt == nil {
+		t.Fatal("an edit that kept the expiry cleared the expiry notice")
+	}
+
+	got.ExpiresAt = got.ExpiresAt.Add(24 * time.Hour)
+	if err := links.Update(got); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = links.ByToken(link.Token)
+	if got.ExpiryNotifiedAt != nil {
+		t.Error("extending the link kept the old expiry notice")
+	}
+}
@@ -0,0 +1,16 @@
+package store
+
+import (
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// To open the database a DATABASE_URL names: sqlite:<path> for an SQLite
+// file, anything else is a PostgreSQL connection string
+func Open(dsn string) (*gorm.DB, error) {
+	if IsSQLite(dsn) {
+		return openSQLite(dsn)
+	}
+
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+}
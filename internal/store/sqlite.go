@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	// To register the pure Go "sqlite" driver, so the server builds without cgo
+	_ "modernc.org/sqlite"
+)
+
+const sqlitePrefix = "sqlite:"
+
+// To tell whether a DATABASE_URL names an SQLite file, such as
+// sqlite:privycode.db, sqlite:///var/lib/privycode.db or sqlite::memory:
+func IsSQLite(dsn string) bool {
+	return strings.HasPrefix(dsn, sqlitePrefix)
+}
+
+// To open an SQLite database with foreign keys enforced. It gets a single
+// connection: SQLite allows one writer at a time anyway, and an in-memory
+// database only lives as long as its connection.
+func openSQLite(dsn string) (*gorm.DB, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(dsn, sqlitePrefix), "//")
+	if path == "" {
+		return nil, errors.New("an SQLite DATABASE_URL needs a file, e.g. sqlite:privycode.db")
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	path += separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	database, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: "sqlite", DSN: path}), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := database.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return database, nil
+}
@@ -0,0 +1,73 @@
+// Package store is where viewer links and users are read and written.
+// Handlers depend on the LinkStore and UserStore interfaces; the GORM
+// implementations back them with PostgreSQL in production or with an
+// embedded SQLite file for tests and single-binary installs. Open picks
+// the database from DATABASE_URL.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when no row matches
+	ErrNotFound = errors.New("not found")
+
+	// ErrViewLimitReached is returned by AddView once a link has used up its views
+	ErrViewLimitReached = errors.New("view limit reached")
+)
+
+// LinkStore finds and saves viewer links. Lookups skip soft-deleted links
+// unless they say otherwise.
+type LinkStore interface {
+	// ByToken finds a link by the token in its viewer URL
+	ByToken(token string) (*models.ViewerLink, error)
+
+	// ByTokenWithDeleted is ByToken including soft-deleted links, so callers
+	// can tell a deleted link from one that never existed
+	ByTokenWithDeleted(token string) (*models.ViewerLink, error)
+
+	// ByPublicID finds one of the user's links by its public ID
+	ByPublicID(userID uint, publicID string) (*models.ViewerLink, error)
+
+	// ForUser lists the user's links
+	ForUser(userID uint) ([]models.ViewerLink, error)
+
+	Create(link *models.ViewerLink) error
+
+	// Update saves the settings an owner can edit. Counters that viewers
+	// change (views, failed unlocks, lockouts) are left as they are in the
+	// database, so a concurrent view is never undone. Moving ExpiresAt clears
+	// the expiry notices, so they are sent again for the new date.
+	Update(link *models.ViewerLink) error
+	Delete(link *models.ViewerLink) error
+
+	// AddView counts a view and creates the viewer session that made it, in
+	// one transaction. The count is checked and incremented atomically, so
+	// concurrent viewers cannot overshoot MaxViews; ErrViewLimitReached is
+	// returned once it is reached. link.ViewCount is set to the new count.
+	AddView(link *models.ViewerLink, session *models.ViewerSession) error
+
+	// RecordFailedUnlock counts a wrong password and, once maxFailed is
+	// reached, clears the count and locks the link until lockedUntil
+	RecordFailedUnlock(link *models.ViewerLink, maxFailed int, lockedUntil time.Time) error
+
+	// ResetFailedUnlocks clears the count and any lockout after a correct password
+	ResetFailedUnlocks(link *models.ViewerLink) error
+
+	// MarkExpiryNotified records when the link was marked expired. It reports
+	// false if the link was already marked, so link.expired is sent only once.
+	MarkExpiryNotified(link *models.ViewerLink, at time.Time) (bool, error)
+}
+
+// UserStore finds and saves users
+type UserStore interface {
+	ByID(id uint) (*models.User, error)
+	ByGitHubUsername(username string) (*models.User, error)
+
+	Create(user *models.User) error
+	Save(user *models.User) error
+}
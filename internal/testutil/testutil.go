@@ -0,0 +1,99 @@
+// Package testutil sets up what tests across packages share: a migrated
+// in-memory SQLite database with a test keyring, and users with live sessions.
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"github.com/greatdaveo/privycode-server/internal/secrets"
+	"github.com/greatdaveo/privycode-server/internal/store"
+	"github.com/greatdaveo/privycode-server/internal/utils"
+	"github.com/greatdaveo/privycode-server/migrations"
+	"gorm.io/gorm"
+)
+
+// KeyringSpec is the single test key, in TOKEN_ENCRYPTION_KEYS format
+const KeyringSpec = "test:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+// To open a fresh, migrated in-memory database with the test keyring
+// attached. It is closed when the test ends.
+func DB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	keyring, err := secrets.ParseKeyring(KeyringSpec, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := store.Open("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return models.WithKeyring(db, keyring)
+}
+
+// To create a user with a GitHub token
+func User(t testing.TB, db *gorm.DB, username string) *models.User {
+	t.Helper()
+
+	user := models.User{Email: username + "@example.com", GitHubUsername: username, GitHubToken: "gh-" + username}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return &user
+}
+
+// To create a session for user that lasts until expiresAt, returning its
+// Authorization header value
+func Session(t testing.TB, db *gorm.DB, user *models.User, expiresAt time.Time) string {
+	t.Helper()
+
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := models.Session{UserID: user.ID, TokenHash: utils.HashToken(token), ExpiresAt: expiresAt}
+	if err := db.Create(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return "Bearer " + token
+}
+
+// To create a public viewer link to repoName, owned by user
+func Link(t testing.TB, db *gorm.DB, user *models.User, repoName string, maxViews int) *models.ViewerLink {
+	t.Helper()
+
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := models.ViewerLink{
+		RepoName:   repoName,
+		UserID:     user.ID,
+		Token:      token,
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+		MaxViews:   maxViews,
+		AccessMode: models.AccessPublic,
+	}
+	if err := db.Create(&link).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return &link
+}
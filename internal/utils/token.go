@@ -1,8 +1,31 @@
 package utils
 
-import "github.com/google/uuid"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+)
 
 // To create a new UUID token string
 func GenerateToken() string {
 	return uuid.New().String()
 }
+
+// To create an opaque, URL-safe random token (32 bytes of entropy)
+func GenerateSecureToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// To hash a token before storing or looking it up, so the DB never holds the raw value
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
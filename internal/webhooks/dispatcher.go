@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxAttempts = 8
+	defaultBaseDelay   = 30 * time.Second
+	defaultMaxDelay    = 6 * time.Hour
+	defaultBatchSize   = 50
+
+	// How long a claimed delivery is hidden from other dispatchers while it is sent
+	claimLease = 2 * time.Minute
+)
+
+// Dispatcher sends queued deliveries. Several dispatchers (one per server
+// instance) can share a database; each delivery is claimed before it is sent.
+type Dispatcher struct {
+	DB *gorm.DB
+
+	// HTTPClient refuses internal addresses and redirects; see newHTTPClient
+	HTTPClient *http.Client
+
+	// Clock decides which deliveries are due and when failed ones are retried
+	Clock  func() time.Time
+	Logger *log.Logger
+
+	// A failed delivery is retried after BaseDelay, doubling each time up to
+	// MaxDelay, and marked failed after MaxAttempts attempts
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	BatchSize   int
+}
+
+// To create a dispatcher with the default retry policy
+func NewDispatcher(db *gorm.DB, clock func() time.Time, logger *log.Logger) *Dispatcher {
+	return &Dispatcher{
+		DB:          db,
+		HTTPClient:  newHTTPClient(),
+		Clock:       clock,
+		Logger:      logger,
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+		BatchSize:   defaultBatchSize,
+	}
+}
+
+// To send due deliveries every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.ProcessDue(ctx); err != nil {
+			d.Logger.Printf("❌ Webhook dispatch failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// To send every pending delivery whose next attempt is due, returning how many were attempted
+func (d *Dispatcher) ProcessDue(ctx context.Context) (int, error) {
+	var due []models.WebhookDelivery
+	err := d.DB.
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, d.Clock()).
+		Order("next_attempt_at").
+		Limit(d.BatchSize).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		claimed, err := d.claim(&due[i])
+		if err != nil {
+			return attempted, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := d.Deliver(ctx, &due[i]); err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+// To take a due delivery for this dispatcher by pushing its next attempt past
+// the lease. Returns false if another dispatcher got to it first.
+func (d *Dispatcher) claim(delivery *models.WebhookDelivery) (bool, error) {
+	now := d.Clock()
+	leaseUntil := now.Add(claimLease)
+	result := d.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, models.DeliveryPending, now).
+		UpdateColumn("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	delivery.NextAttemptAt = leaseUntil
+	return result.RowsAffected == 1, nil
+}
+
+// To attempt a freshly queued delivery right away instead of waiting for
+// the next tick. It is claimed first so no other dispatcher sends it too.
+func (d *Dispatcher) DeliverNow(ctx context.Context, delivery *models.WebhookDelivery) error {
+	claimed, err := d.claim(delivery)
+	if err != nil || !claimed {
+		return err
+	}
+
+	return d.Deliver(ctx, delivery)
+}
+
+// To make one attempt at a delivery and record the outcome. The returned
+// error is about recording it; a failed send is recorded on the delivery.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	var webhook models.Webhook
+	err := d.DB.First(&webhook, delivery.WebhookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return d.DB.Model(delivery).Updates(map[string]any{
+			"status":     models.DeliveryFailed,
+			"last_error": "webhook was removed",
+		}).Error
+	} else if err != nil {
+		return err
+	}
+
+	statusCode, sendErr := d.send(ctx, &webhook, delivery)
+
+	now := d.Clock()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = sendErr.Error()
+	default:
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(d.Backoff(delivery.Attempts))
+	}
+
+	return d.DB.Model(delivery).Select("status", "attempts", "last_status_code", "last_error", "next_attempt_at", "delivered_at").Updates(delivery).Error
+}
+
+// To compute the wait before the next attempt, after the given number of attempts
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < attempts && delay < d.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.MaxDelay)
+}
+
+// To POST the signed payload; anything but a 2xx answer is an error
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PrivyCode-Webhooks")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.UUID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// To record only the status: the response body must never reach the user,
+	// or a webhook could be used to read pages it should not see
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,95 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for webhook URLs that resolve to this
+// server or its private network, which users must not be able to reach
+var ErrBlockedAddress = errors.New("webhook address is not publicly routable")
+
+// To build the client deliveries are sent with. It checks every address it
+// connects to, after DNS resolution, so a public name that resolves to an
+// internal address is refused too. It does not follow redirects, which could
+// otherwise bounce a delivery to an internal host, and ignores proxy settings
+// so the check sees the real destination.
+func newHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+
+			if blockedAddr(addr) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// To tell whether a webhook URL host is obviously internal: localhost or an
+// IP literal in a blocked range. Names are checked again when dialled.
+func BlockedHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(host, "[]"))
+	return err == nil && blockedAddr(addr)
+}
+
+// Special-purpose ranges the netip predicates do not cover
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network", reaches the host itself on Linux
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, often internal in clouds
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which embeds any IPv4 address
+}
+
+// To refuse loopback, private, link-local (including cloud metadata at
+// 169.254.169.254), multicast, unspecified and the blockedPrefixes
+// addresses. IPv4-mapped IPv6 addresses are checked as IPv4.
+func blockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
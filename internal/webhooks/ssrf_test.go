@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBlockedHost(t *testing.T) {
+	blocked := []string{"localhost", "api.localhost", "127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1",
+		"169.254.169.254", "0.0.0.0", "::1", "[::1]", "fe80::1", "fd00::1", "::ffff:127.0.0.1",
+		"0.1.2.3", "100.64.0.1", "100.127.255.254", "198.18.0.1", "198.19.255.255",
+		"64:ff9b::a9fe:a9fe", "[64:ff9b::7f00:1]", "::ffff:10.0.0.1", "::ffff:100.64.0.1"}
+	for _, host := range blocked {
+		if !BlockedHost(host) {
+			t.Errorf("BlockedHost(%q) = false, want true", host)
+		}
+	}
+
+	allowed := []string{"example.com", "hooks.slack.com", "8.8.8.8", "2606:4700::1111",
+		"100.128.0.1", "198.20.0.1", "::ffff:8.8.8.8"}
+	for _, host := range allowed {
+		if BlockedHost(host) {
+			t.Errorf("BlockedHost(%q) = true, want false", host)
+		}
+	}
+}
+
+func TestHTTPClientRefusesLoopback(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	_, err := newHTTPClient().Post(server.URL, "application/json", nil)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("err = %v, want ErrBlockedAddress", err)
+	}
+	if reached {
+		t.Fatal("request reached the loopback server")
+	}
+}
@@ -0,0 +1,140 @@
+// Package webhooks tells link owners' own systems about activity on their
+// viewer links. Events are queued as WebhookDelivery rows and sent by a
+// Dispatcher as HMAC-signed JSON.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/greatdaveo/privycode-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EventLinkCreated      = "link.created"
+	EventLinkUpdated      = "link.updated"
+	EventLinkDeleted      = "link.deleted"
+	EventLinkFirstOpened  = "link.first_opened"
+	EventViewLimitReached = "link.view_limit_reached"
+	EventLinkExpired      = "link.expired"
+	EventTest             = "webhook.test"
+)
+
+// Events lists what a webhook can subscribe to
+var Events = []string{
+	EventLinkCreated,
+	EventLinkUpdated,
+	EventLinkDeleted,
+	EventLinkFirstOpened,
+	EventViewLimitReached,
+	EventLinkExpired,
+}
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body,
+	// keyed by the webhook secret
+	SignatureHeader = "X-PrivyCode-Signature"
+	EventHeader     = "X-PrivyCode-Event"
+	DeliveryHeader  = "X-PrivyCode-Delivery"
+)
+
+// Payload is the JSON body of every delivery
+type Payload struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// LinkData describes the viewer link an event is about
+type LinkData struct {
+	ID        string    `json:"id"`
+	RepoName  string    `json:"repo_name"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxViews  int       `json:"max_views"`
+	ViewCount int       `json:"view_count"`
+}
+
+// To describe a link for an event payload
+func Link(link *models.ViewerLink) LinkData {
+	return LinkData{
+		ID:        link.PublicID,
+		RepoName:  link.RepoName,
+		ExpiresAt: link.ExpiresAt,
+		MaxViews:  link.MaxViews,
+		ViewCount: link.ViewCount,
+	}
+}
+
+// To check that every event name is one a webhook can subscribe to
+func ValidateEvents(events []string) error {
+	for _, event := range events {
+		known := false
+		for _, candidate := range Events {
+			if event == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return errors.New("unknown webhook event: " + event)
+		}
+	}
+
+	return nil
+}
+
+// To sign a payload body with the webhook secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// To queue an event for the user's webhook, due right away at now. Users without
+// an active webhook subscribed to the event are skipped, and a nil delivery is returned.
+func Enqueue(db *gorm.DB, now time.Time, userID uint, event string, data any) (*models.WebhookDelivery, error) {
+	// To use Find, not First: most users have no webhook, and First would log
+	// every miss as an error
+	var webhook models.Webhook
+	result := db.Where("user_id = ?", userID).Limit(1).Find(&webhook)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	if event != EventTest && !webhook.Subscribed(event) {
+		return nil, nil
+	}
+
+	id := uuid.NewString()
+	body, err := json.Marshal(Payload{ID: id, Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		Webhook:       webhook,
+		UUID:          id,
+		Event:         event,
+		Payload:       string(body),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+	}
+
+	// To keep the webhook row as it is; only the delivery is new
+	if err := db.Omit("Webhook").Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
@@ -0,0 +1,203 @@
+// Package migrations holds the database schema as numbered up/down SQL files
+// and applies them with golang-migrate. There is one set per database,
+// postgres/ and sqlite/, with the same version numbers. The files are
+// embedded, so the server binary carries the schema it expects.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"gorm.io/gorm"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Status is where the schema stands against the embedded migrations
+type Status struct {
+	Version uint   // last applied migration, 0 if none
+	Dirty   bool   // a migration failed half way and needs fixing by hand
+	Latest  uint   // newest embedded migration
+	Pending []uint // embedded migrations not applied yet, oldest first
+}
+
+// To tell whether the server can run against this schema
+func (s Status) Current() bool {
+	return !s.Dirty && len(s.Pending) == 0
+}
+
+// To apply every pending migration
+func Up(db *gorm.DB) error {
+	return run(db, func(m *migrate.Migrate) error {
+		return m.Up()
+	})
+}
+
+// To roll back the last steps migrations
+func Down(db *gorm.DB, steps int) error {
+	if steps <= 0 {
+		return errors.New("steps must be at least 1")
+	}
+
+	return run(db, func(m *migrate.Migrate) error {
+		return m.Steps(-steps)
+	})
+}
+
+// To report the applied and pending migrations
+func Current(db *gorm.DB) (Status, error) {
+	var status Status
+	err := run(db, func(m *migrate.Migrate) error {
+		version, dirty, err := m.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return err
+		}
+		status.Version, status.Dirty = version, dirty
+		return nil
+	})
+	if err != nil {
+		return status, err
+	}
+
+	versions, err := Versions(db.Dialector.Name())
+	if err != nil {
+		return status, err
+	}
+
+	for _, version := range versions {
+		if version > status.Version {
+			status.Pending = append(status.Pending, version)
+		}
+		status.Latest = version
+	}
+
+	return status, nil
+}
+
+// To refuse a schema the server is not written for: one that is behind the
+// embedded migrations or left dirty by a failed one
+func Check(db *gorm.DB) error {
+	status, err := Current(db)
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		return fmt.Errorf("schema is dirty at version %d: fix the failed migration by hand, then clear schema_migrations.dirty", status.Version)
+	}
+
+	if len(status.Pending) > 0 {
+		return fmt.Errorf("schema is at version %d but the server needs %d: run `migrate up`", status.Version, status.Latest)
+	}
+
+	return nil
+}
+
+// To list the embedded migration versions for a database ("postgres" or
+// "sqlite"), oldest first
+func Versions(dialect string) ([]uint, error) {
+	src, err := openSource(dialect)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	var versions []uint
+	for err == nil {
+		versions = append(versions, version)
+		version, err = src.Next(version)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// To run fn against the database. The pool itself stays open afterwards.
+func run(db *gorm.DB, fn func(m *migrate.Migrate) error) error {
+	dialect := db.Dialector.Name()
+
+	src, err := openSource(dialect)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	driver, release, err := openDriver(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// To leave m unclosed: closing it would close the driver, and the SQLite
+	// driver closes the whole pool
+	m, err := migrate.NewWithInstance("iofs", src, dialect, driver)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(m); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	return nil
+}
+
+// To open the embedded migrations for a database
+func openSource(dialect string) (source.Driver, error) {
+	if _, err := fs.Stat(files, dialect); err != nil {
+		return nil, fmt.Errorf("no migrations for %s databases", dialect)
+	}
+
+	return iofs.New(files, dialect)
+}
+
+// To wrap db for golang-migrate. PostgreSQL gets a dedicated connection,
+// returned to the pool by release; SQLite shares the pool, which is a
+// single connection.
+func openDriver(db *gorm.DB) (driver database.Driver, release func(), err error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch db.Dialector.Name() {
+	case "postgres":
+		ctx := context.Background()
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+
+		return driver, func() { driver.Close() }, nil
+
+	case "sqlite":
+		driver, err := sqlite.WithInstance(sqlDB, &sqlite.Config{})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return driver, func() {}, nil
+	}
+
+	return nil, nil, fmt.Errorf("versioned migrations need PostgreSQL or SQLite, not %s", db.Dialector.Name())
+}
@@ -0,0 +1,174 @@
+-- The schema as AutoMigrate left it. Every statement is guarded so that
+-- databases created by AutoMigrate can adopt versioned migrations as they are.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    email text NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    git_hub_username text NOT NULL CONSTRAINT uni_users_git_hub_username UNIQUE
+);
+
+-- Columns added to users after it was first created
+ALTER TABLE users ADD COLUMN IF NOT EXISTS git_hub_api_url text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS git_hub_token_ciphertext text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS git_hub_token_key_id text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_first_opened boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_expiring boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS weekly_digest boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_digest_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS viewer_links (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    repo_name text NOT NULL,
+    user_id bigint NOT NULL CONSTRAINT fk_users_viewer_links REFERENCES users (id) ON DELETE CASCADE,
+    token text NOT NULL CONSTRAINT uni_viewer_links_token UNIQUE,
+    expires_at timestamptz,
+    max_views bigint,
+    view_count bigint
+);
+
+-- Columns added to viewer_links after it was first created
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS public_id varchar(36);
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS ref text;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS commit_sha text;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS follow_branch boolean;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS allow_paths text;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS deny_paths text;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS disable_redaction boolean;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS password_hash text;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS failed_unlocks bigint NOT NULL DEFAULT 0;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS locked_until timestamptz;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS access_mode text NOT NULL DEFAULT 'public';
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS allowed_emails text;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS expiry_notified_at timestamptz;
+ALTER TABLE viewer_links ADD COLUMN IF NOT EXISTS expiry_reminder_sent_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_viewer_links_deleted_at ON viewer_links (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_links_public_id ON viewer_links (public_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL CONSTRAINT fk_sessions_user REFERENCES users (id) ON DELETE CASCADE,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    last_used_at timestamptz,
+    user_agent text
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_token_hash ON sessions (token_hash);
+
+CREATE TABLE IF NOT EXISTS o_auth_states (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    state_hash text NOT NULL,
+    code_verifier text NOT NULL,
+    return_to text,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_o_auth_states_deleted_at ON o_auth_states (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_o_auth_states_state_hash ON o_auth_states (state_hash);
+
+CREATE TABLE IF NOT EXISTS viewer_sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    viewer_link_id bigint NOT NULL CONSTRAINT fk_viewer_sessions_viewer_link REFERENCES viewer_links (id) ON DELETE CASCADE,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    email text
+);
+
+-- Columns added to viewer_sessions after it was first created
+ALTER TABLE viewer_sessions ADD COLUMN IF NOT EXISTS last_seen_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_viewer_sessions_deleted_at ON viewer_sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_viewer_sessions_viewer_link_id ON viewer_sessions (viewer_link_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_sessions_token_hash ON viewer_sessions (token_hash);
+CREATE INDEX IF NOT EXISTS idx_viewer_sessions_last_seen_at ON viewer_sessions (last_seen_at);
+
+CREATE TABLE IF NOT EXISTS viewer_magic_links (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    viewer_link_id bigint NOT NULL CONSTRAINT fk_viewer_magic_links_viewer_link REFERENCES viewer_links (id) ON DELETE CASCADE,
+    email text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    used_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_viewer_magic_links_deleted_at ON viewer_magic_links (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_viewer_magic_links_viewer_link_id ON viewer_magic_links (viewer_link_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_magic_links_token_hash ON viewer_magic_links (token_hash);
+
+CREATE TABLE IF NOT EXISTS view_events (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    viewer_link_id bigint NOT NULL,
+    endpoint text NOT NULL,
+    path text,
+    ip_hash text,
+    user_agent text,
+    viewer_session_id bigint,
+    viewer_email text,
+    referrer text
+);
+
+CREATE INDEX IF NOT EXISTS idx_view_events_created_at ON view_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_view_events_viewer_link_id ON view_events (viewer_link_id);
+CREATE INDEX IF NOT EXISTS idx_view_events_viewer_session_id ON view_events (viewer_session_id);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL CONSTRAINT fk_webhooks_user REFERENCES users (id) ON DELETE CASCADE,
+    url text NOT NULL,
+    events text,
+    active boolean NOT NULL DEFAULT true,
+    secret_ciphertext text NOT NULL DEFAULT '',
+    secret_key_id text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON webhooks (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    webhook_id bigint NOT NULL CONSTRAINT fk_webhook_deliveries_webhook REFERENCES webhooks (id) ON DELETE CASCADE,
+    uuid text NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    next_attempt_at timestamptz,
+    attempts bigint NOT NULL DEFAULT 0,
+    last_status_code bigint,
+    last_error text,
+    delivered_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_uuid ON webhook_deliveries (uuid);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
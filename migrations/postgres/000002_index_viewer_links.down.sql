@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_viewer_links_user_id;
+
+ALTER TABLE viewer_links ADD CONSTRAINT uni_viewer_links_token UNIQUE (token);
+DROP INDEX IF EXISTS idx_viewer_links_token;
@@ -0,0 +1,7 @@
+-- Viewer requests look links up by token and the dashboard lists them by
+-- owner. The unique index replaces AutoMigrate's uni_viewer_links_token
+-- constraint so that token is indexed once.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_links_token ON viewer_links (token);
+ALTER TABLE viewer_links DROP CONSTRAINT IF EXISTS uni_viewer_links_token;
+
+CREATE INDEX IF NOT EXISTS idx_viewer_links_user_id ON viewer_links (user_id);
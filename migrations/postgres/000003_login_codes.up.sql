@@ -0,0 +1,15 @@
+-- The OAuth callback redirects with a single-use login code instead of the
+-- session token; the frontend exchanges it at POST /auth/exchange.
+CREATE TABLE IF NOT EXISTS login_codes (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL CONSTRAINT fk_login_codes_user REFERENCES users (id) ON DELETE CASCADE,
+    code_hash text NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_codes_deleted_at ON login_codes (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_login_codes_user_id ON login_codes (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_codes_code_hash ON login_codes (code_hash);
@@ -0,0 +1,6 @@
+-- Every replica runs the job scheduler; the last run of each job is recorded
+-- here so a job runs once per interval across the cluster, not once per replica.
+CREATE TABLE IF NOT EXISTS job_runs (
+    name text PRIMARY KEY,
+    last_run_at timestamptz NOT NULL
+);
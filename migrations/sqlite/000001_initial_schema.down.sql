@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS view_events;
+DROP TABLE IF EXISTS viewer_magic_links;
+DROP TABLE IF EXISTS viewer_sessions;
+DROP TABLE IF EXISTS o_auth_states;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS viewer_links;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,169 @@
+-- The schema of postgres/000001 for SQLite, using the column types GORM
+-- expects there: integer ids, datetime timestamps and numeric booleans.
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    email text NOT NULL CONSTRAINT uni_users_email UNIQUE,
+    git_hub_username text NOT NULL CONSTRAINT uni_users_git_hub_username UNIQUE,
+    git_hub_api_url text,
+    git_hub_token_ciphertext text NOT NULL DEFAULT '',
+    git_hub_token_key_id text NOT NULL DEFAULT '',
+    notify_first_opened numeric NOT NULL DEFAULT false,
+    notify_expiring numeric NOT NULL DEFAULT false,
+    weekly_digest numeric NOT NULL DEFAULT false,
+    last_digest_at datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+-- token becomes unique in 000002, as it does on PostgreSQL
+CREATE TABLE IF NOT EXISTS viewer_links (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    public_id text,
+    repo_name text NOT NULL,
+    user_id integer NOT NULL CONSTRAINT fk_users_viewer_links REFERENCES users (id) ON DELETE CASCADE,
+    token text NOT NULL,
+    expires_at datetime,
+    max_views integer,
+    view_count integer,
+    ref text,
+    commit_sha text,
+    follow_branch numeric,
+    allow_paths text,
+    deny_paths text,
+    disable_redaction numeric,
+    password_hash text,
+    failed_unlocks integer NOT NULL DEFAULT 0,
+    locked_until datetime,
+    access_mode text NOT NULL DEFAULT 'public',
+    allowed_emails text,
+    expiry_notified_at datetime,
+    expiry_reminder_sent_at datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_viewer_links_deleted_at ON viewer_links (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_links_public_id ON viewer_links (public_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL CONSTRAINT fk_sessions_user REFERENCES users (id) ON DELETE CASCADE,
+    token_hash text NOT NULL,
+    expires_at datetime NOT NULL,
+    last_used_at datetime,
+    user_agent text
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_token_hash ON sessions (token_hash);
+
+CREATE TABLE IF NOT EXISTS o_auth_states (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    state_hash text NOT NULL,
+    code_verifier text NOT NULL,
+    return_to text,
+    expires_at datetime NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_o_auth_states_deleted_at ON o_auth_states (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_o_auth_states_state_hash ON o_auth_states (state_hash);
+
+CREATE TABLE IF NOT EXISTS viewer_sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    viewer_link_id integer NOT NULL CONSTRAINT fk_viewer_sessions_viewer_link REFERENCES viewer_links (id) ON DELETE CASCADE,
+    token_hash text NOT NULL,
+    expires_at datetime NOT NULL,
+    last_seen_at datetime,
+    email text
+);
+
+CREATE INDEX IF NOT EXISTS idx_viewer_sessions_deleted_at ON viewer_sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_viewer_sessions_viewer_link_id ON viewer_sessions (viewer_link_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_sessions_token_hash ON viewer_sessions (token_hash);
+CREATE INDEX IF NOT EXISTS idx_viewer_sessions_last_seen_at ON viewer_sessions (last_seen_at);
+
+CREATE TABLE IF NOT EXISTS viewer_magic_links (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    viewer_link_id integer NOT NULL CONSTRAINT fk_viewer_magic_links_viewer_link REFERENCES viewer_links (id) ON DELETE CASCADE,
+    email text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at datetime NOT NULL,
+    used_at datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_viewer_magic_links_deleted_at ON viewer_magic_links (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_viewer_magic_links_viewer_link_id ON viewer_magic_links (viewer_link_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_magic_links_token_hash ON viewer_magic_links (token_hash);
+
+CREATE TABLE IF NOT EXISTS view_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    viewer_link_id integer NOT NULL,
+    endpoint text NOT NULL,
+    path text,
+    ip_hash text,
+    user_agent text,
+    viewer_session_id integer,
+    viewer_email text,
+    referrer text
+);
+
+CREATE INDEX IF NOT EXISTS idx_view_events_created_at ON view_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_view_events_viewer_link_id ON view_events (viewer_link_id);
+CREATE INDEX IF NOT EXISTS idx_view_events_viewer_session_id ON view_events (viewer_session_id);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL CONSTRAINT fk_webhooks_user REFERENCES users (id) ON DELETE CASCADE,
+    url text NOT NULL,
+    events text,
+    active numeric NOT NULL DEFAULT true,
+    secret_ciphertext text NOT NULL DEFAULT '',
+    secret_key_id text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON webhooks (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    webhook_id integer NOT NULL CONSTRAINT fk_webhook_deliveries_webhook REFERENCES webhooks (id) ON DELETE CASCADE,
+    uuid text NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    next_attempt_at datetime,
+    attempts integer NOT NULL DEFAULT 0,
+    last_status_code integer,
+    last_error text,
+    delivered_at datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_uuid ON webhook_deliveries (uuid);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
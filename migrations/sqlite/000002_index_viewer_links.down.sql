@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_viewer_links_user_id;
+DROP INDEX IF EXISTS idx_viewer_links_token;
@@ -0,0 +1,4 @@
+-- Viewer requests look links up by token and the dashboard lists them by
+-- owner.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_viewer_links_token ON viewer_links (token);
+CREATE INDEX IF NOT EXISTS idx_viewer_links_user_id ON viewer_links (user_id);